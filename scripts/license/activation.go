@@ -0,0 +1,189 @@
+package license
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWrongMachine is returned when a host-bound license is checked on a
+	// machine other than the one it was activated for.
+	ErrWrongMachine = errors.New("license: bound to another machine")
+	// ErrActivationMismatch is returned when a license does not answer the
+	// activation request it is being accepted for.
+	ErrActivationMismatch = errors.New("license: does not answer this activation request")
+)
+
+// MachineIDEnv overrides the machine ID, for containers whose /etc/machine-id
+// is regenerated on every start.
+const MachineIDEnv = "DRIFTLOCK_MACHINE_ID"
+
+// machineIDFiles are read in order; the first non-empty one wins.
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// MachineFingerprint identifies this installation: a hex SHA-256 of
+// $DRIFTLOCK_MACHINE_ID, the systemd machine ID or, failing both, the
+// hostname. Only the hash leaves the machine.
+func MachineFingerprint() (string, error) {
+	id := strings.TrimSpace(os.Getenv(MachineIDEnv))
+	for _, path := range machineIDFiles {
+		if id != "" {
+			break
+		}
+		if b, err := os.ReadFile(path); err == nil {
+			id = strings.TrimSpace(string(b))
+		}
+	}
+	if id == "" {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			return "", fmt.Errorf("machine fingerprint: no machine ID or hostname available (set $%s)", MachineIDEnv)
+		}
+		id = host
+	}
+	return HashMachineID(id), nil
+}
+
+// HashMachineID returns the fingerprint of a raw machine ID.
+func HashMachineID(id string) string {
+	sum := sha256.Sum256([]byte("driftlock-machine:" + id))
+	return hex.EncodeToString(sum[:])
+}
+
+// ActivationRequest is the challenge an offline installation hands to the
+// issuer. The signed license echoes Nonce back as its activation ID, so the
+// installation can tell the response belongs to its own request. InstallKey,
+// if set, is the public key the installation will sign usage reports with.
+type ActivationRequest struct {
+	Version     int    `json:"version"`
+	Machine     string `json:"machine"`
+	Hostname    string `json:"hostname,omitempty"`
+	Nonce       string `json:"nonce"`
+	RequestedAt int64  `json:"requested_at"`
+	License     string `json:"license,omitempty"`
+	InstallKey  string `json:"install_key,omitempty"`
+}
+
+// NewActivationRequest builds a request for this machine. existing is the
+// license being activated, if the installation already holds one.
+func NewActivationRequest(existing string, now time.Time) (*ActivationRequest, error) {
+	machine, err := MachineFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	return &ActivationRequest{
+		Version:     1,
+		Machine:     machine,
+		Hostname:    host,
+		Nonce:       hex.EncodeToString(nonce),
+		RequestedAt: now.Unix(),
+		License:     strings.TrimSpace(existing),
+	}, nil
+}
+
+// Validate reports whether the request is well formed.
+func (r *ActivationRequest) Validate() error {
+	if r.Version != 1 {
+		return fmt.Errorf("activation request: unsupported version %d", r.Version)
+	}
+	if !isHex(r.Machine, sha256.Size) {
+		return fmt.Errorf("activation request: machine must be a hex SHA-256 fingerprint")
+	}
+	if !isHex(r.Nonce, 16) {
+		return fmt.Errorf("activation request: nonce must be 16 bytes of hex")
+	}
+	if r.InstallKey != "" {
+		if _, err := ParsePublicKeyHex(r.InstallKey); err != nil {
+			return fmt.Errorf("activation request: install key: %v", err)
+		}
+	}
+	return nil
+}
+
+func isHex(s string, n int) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == n
+}
+
+// ReadActivationRequest loads and validates an activation request file.
+func ReadActivationRequest(path string) (*ActivationRequest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r ActivationRequest
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&r); err != nil {
+		return nil, fmt.Errorf("activation request %s: %w", path, err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// WriteActivationRequest saves r to path.
+func WriteActivationRequest(path string, r *ActivationRequest) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Bind ties claims to the machine, nonce and installation key of an
+// activation request.
+func (c *Claims) Bind(r *ActivationRequest) {
+	c.Machine = r.Machine
+	c.ActivationID = r.Nonce
+	c.InstallKey = r.InstallKey
+}
+
+// Accept verifies key on the installation that wrote r and checks that it
+// answers r: bound to r's machine, carrying r's nonce as its activation ID
+// and naming r's installation key. v's Machine is ignored in favour of r's.
+func (r *ActivationRequest) Accept(v *Verifier, key string, now time.Time) (*License, error) {
+	bound := *v
+	bound.Machine = r.Machine
+	lic, err := bound.Verify(key, now)
+	if err != nil {
+		return lic, err
+	}
+	c := lic.Claims
+	switch {
+	case c == nil || c.Machine == "":
+		return lic, fmt.Errorf("%w: license is not host-bound", ErrActivationMismatch)
+	case c.ActivationID != r.Nonce:
+		return lic, fmt.Errorf("%w: activation ID %s, request nonce %s", ErrActivationMismatch, c.ActivationID, r.Nonce)
+	case c.InstallKey != r.InstallKey:
+		return lic, fmt.Errorf("%w: installation key differs from the request's", ErrActivationMismatch)
+	}
+	return lic, nil
+}
+
+// checkMachine rejects host-bound licenses presented on another machine.
+func checkMachine(lic *License, machine string) error {
+	if lic.Claims == nil || lic.Claims.Machine == "" {
+		return nil
+	}
+	if machine == "" {
+		return fmt.Errorf("%w: license is host-bound but no machine fingerprint was supplied", ErrWrongMachine)
+	}
+	if machine != lic.Claims.Machine {
+		return fmt.Errorf("%w: license is bound to %.16s…, this machine is %.16s…", ErrWrongMachine, lic.Claims.Machine, machine)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package license
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Plans and feature flags accepted in v2 license claims.
+var (
+	Plans    = []string{"pulse", "radar", "lock", "orbit"}
+	Features = []string{"ai_explanations", "dora_export", "openzl"}
+)
+
+// maxSigners bounds Claims.Signers so a license stays a pasteable length.
+const maxSigners = 8
+
+// Claims is the signed payload of a v2 license. Field order is fixed and
+// Features is kept sorted, so the JSON encoding is canonical. Machine and
+// ActivationID are set only on host-bound licenses (see ActivationRequest),
+// along with InstallKey if the installation signs usage reports;
+// Previous is the fingerprint of the license a renewal or upgrade replaced;
+// Signers, if above one, is how many distinct keys must sign the license.
+type Claims struct {
+	Version        int      `json:"v"`
+	TenantID       string   `json:"tenant_id"`
+	Tier           string   `json:"tier"`
+	Plan           string   `json:"plan"`
+	EventsPerMonth int64    `json:"events_per_month"`
+	StreamLimit    int64    `json:"stream_limit"`
+	Features       []string `json:"features"`
+	NotBefore      int64    `json:"nbf"`
+	IssuedAt       int64    `json:"iat"`
+	Expiry         int64    `json:"exp"`
+	Machine        string   `json:"machine,omitempty"`
+	ActivationID   string   `json:"activation_id,omitempty"`
+	Previous       string   `json:"previous,omitempty"`
+	Signers        int      `json:"signers,omitempty"`
+	InstallKey     string   `json:"install_key,omitempty"`
+}
+
+// Validate reports whether the claims are complete and canonical.
+func (c *Claims) Validate() error {
+	if c.TenantID == "" {
+		return fmt.Errorf("claims: tenant ID is required")
+	}
+	if c.Tier == "" {
+		return fmt.Errorf("claims: tier is required")
+	}
+	if !contains(Plans, c.Plan) {
+		return fmt.Errorf("claims: unknown plan %q (expected one of %s)", c.Plan, strings.Join(Plans, ", "))
+	}
+	if c.EventsPerMonth < 0 || c.StreamLimit < 0 {
+		return fmt.Errorf("claims: quotas must not be negative")
+	}
+	if c.Features == nil {
+		return fmt.Errorf("claims: features must be a list")
+	}
+	for _, f := range c.Features {
+		if !contains(Features, f) {
+			return fmt.Errorf("claims: unknown feature %q (expected one of %s)", f, strings.Join(Features, ", "))
+		}
+	}
+	if !sort.StringsAreSorted(c.Features) {
+		return fmt.Errorf("claims: features are not in canonical order")
+	}
+	if c.Expiry <= c.NotBefore {
+		return fmt.Errorf("claims: expiry must be after not-before")
+	}
+	if c.Machine != "" && !isHex(c.Machine, 32) {
+		return fmt.Errorf("claims: machine must be a hex SHA-256 fingerprint")
+	}
+	if c.ActivationID != "" && c.Machine == "" {
+		return fmt.Errorf("claims: activation ID without a machine")
+	}
+	if c.Previous != "" && !isHex(c.Previous, 32) {
+		return fmt.Errorf("claims: previous must be a license fingerprint")
+	}
+	if c.InstallKey != "" {
+		if c.Machine == "" {
+			return fmt.Errorf("claims: install key without a machine")
+		}
+		if _, err := ParsePublicKeyHex(c.InstallKey); err != nil {
+			return fmt.Errorf("claims: install key: %v", err)
+		}
+	}
+	if c.Signers < 0 || c.Signers > maxSigners {
+		return fmt.Errorf("claims: signers must be between 0 and %d", maxSigners)
+	}
+	return nil
+}
+
+// HasFeature reports whether the claims enable feature f.
+func (c *Claims) HasFeature(f string) bool {
+	return contains(c.Features, f)
+}
+
+// SplitFeatures turns a comma-separated list into the canonical sorted,
+// de-duplicated feature list.
+func SplitFeatures(s string) []string {
+	features := []string{}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f != "" && !contains(features, f) {
+			features = append(features, f)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// runActivate handles `activate request|issue|accept`, the offline
+// activation handshake: the installation writes a request naming its machine
+// fingerprint, the issuer answers with a license bound to it, and the
+// installation accepts it only if it answers that request.
+func runActivate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: activate request --out <file> [--license <key>] [--install-key <pub>] | issue --request <file> --keystore <file> [--license <key>] [--revocations <file>] | accept --request <file> --license <key> --keyring <file>\n")
+		return exitUsage
+	}
+	switch args[0] {
+	case "request":
+		return runActivateRequest(args[1:])
+	case "issue":
+		return runActivateIssue(args[1:])
+	case "accept":
+		return runActivateAccept(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown activate command %q\n", args[0])
+		return exitUsage
+	}
+}
+
+// runActivateRequest runs on the installation being activated.
+func runActivateRequest(args []string) int {
+	fs := flag.NewFlagSet("activate request", flag.ContinueOnError)
+	out := fs.String("out", "", "File to write the activation request to")
+	key := fs.String("license", os.Getenv("DRIFTLOCK_LICENSE_KEY"), "License to activate on this machine (defaults to $DRIFTLOCK_LICENSE_KEY)")
+	installKey := fs.String("install-key", "", "Public key file (from keygen --alg ed25519) this installation will sign usage reports with")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *out == "" {
+		fmt.Fprintf(os.Stderr, "Error: --out is required\n")
+		return exitUsage
+	}
+	if *key != "" {
+		if _, err := license.Parse(*key); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --license: %v\n", err)
+			return 1
+		}
+	}
+	req, err := license.NewActivationRequest(*key, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *installKey != "" {
+		b, err := os.ReadFile(*installKey)
+		if err == nil {
+			_, err = license.ParsePublicKeyHex(string(b))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --install-key: %v\n", err)
+			return 1
+		}
+		req.InstallKey = strings.TrimSpace(string(b))
+	}
+	if err := license.WriteActivationRequest(*out, req); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("MACHINE=%s\n", req.Machine)
+	fmt.Fprintf(os.Stderr, "\nWrote %s; send it to your Driftlock contact and keep it.\n", *out)
+	fmt.Fprintf(os.Stderr, "The license you get back will carry activation ID %s; check it with `activate accept --request %s`.\n", req.Nonce, *out)
+	return 0
+}
+
+// runActivateIssue runs on the issuer's side. The bound license keeps the
+// grant of the license being activated, if the request or --license carries
+// a v2 one, and records it as previous; otherwise the claims come from the
+// issue flags. --signers applies either way.
+func runActivateIssue(args []string) int {
+	fs := flag.NewFlagSet("activate issue", flag.ContinueOnError)
+	requestPath := fs.String("request", "", "Activation request written by `activate request`")
+	base := fs.String("license", "", "License to bind (overrides the one in the request)")
+	keyring := fs.String("keyring", "", "Extra trusted keys for checking the license being bound")
+	revocations := fs.String("revocations", "", "Signed revocation list; revoked licenses are not rebound")
+	ledgerPath := fs.String("ledger", "", "Issuance ledger (JSONL) to record the activation in")
+	issuer := fs.String("issuer", defaultIssuer(), "Who is issuing, recorded in the ledger")
+	customer := fs.String("customer", "", "Customer name, recorded in the ledger")
+	var grant claimsFlags
+	grant.register(fs)
+	var signingKey signingKeyFlags
+	signingKey.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *requestPath == "" {
+		fmt.Fprintf(os.Stderr, "Error: --request is required\n")
+		return exitUsage
+	}
+	req, err := license.ReadActivationRequest(*requestPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	priv, err := signingKey.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	now := time.Now()
+	if *base == "" {
+		*base = req.License
+	}
+	var claims license.Claims
+	if *base != "" {
+		trusted, err := loadKeyring(nil, *keyring)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		trusted.Add(priv.Public())
+		prev, err := license.Parse(*base)
+		if err == nil {
+			err = license.VerifySignature(prev, trusted)
+		}
+		if err == nil && *revocations != "" {
+			var rl *license.RevocationList
+			if rl, err = loadRevocationList(*revocations, trusted); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitUsage
+			}
+			err = rl.Check(prev)
+		}
+		if errors.Is(err, license.ErrRevoked) {
+			fmt.Fprintf(os.Stderr, "Error: license being activated: %v\n", err)
+			return exitRevoked
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: license being activated: %v\n", err)
+			return 1
+		}
+		if prev.Claims == nil {
+			fmt.Fprintf(os.Stderr, "Error: only v2 licenses can be host-bound; reissue with --tenant and --plan instead of --license\n")
+			return 1
+		}
+		if prev.Claims.Machine != "" && prev.Claims.Machine != req.Machine {
+			fmt.Fprintf(os.Stderr, "Warning: moving license from machine %.16s… to %.16s…\n", prev.Claims.Machine, req.Machine)
+		}
+		claims = *prev.Claims
+		claims.IssuedAt = now.Unix()
+		claims.Signers = grant.signers
+		claims.Previous = license.Fingerprint(prev)
+	} else if claims, err = grant.claims(now); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	claims.Bind(req)
+
+	lic, err := license.NewV2(claims, license.KeyID(priv.Public()))
+	if err == nil {
+		err = license.CheckSignerPolicy(lic)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var key string
+	if *ledgerPath != "" {
+		ledger, err := license.OpenLedger(*ledgerPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		key, err = issueToLedger(ledger, priv, lic, "activate", *customer, *issuer)
+	} else {
+		key, err = license.Sign(priv, lic)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("DRIFTLOCK_LICENSE_KEY=%s\n", key)
+	fmt.Fprintf(os.Stderr, "\nLicense details:\n")
+	printDetails(lic)
+	if req.Hostname != "" {
+		fmt.Fprintf(os.Stderr, "\nBound to %s; it will not verify on any other machine.\n", req.Hostname)
+	}
+	return 0
+}
+
+// runActivateAccept runs on the installation again, checking the license the
+// issuer sent back against the request it wrote.
+func runActivateAccept(args []string) int {
+	fs := flag.NewFlagSet("activate accept", flag.ContinueOnError)
+	requestPath := fs.String("request", "", "Activation request this installation wrote with `activate request`")
+	key := fs.String("license", "", "License the issuer sent back")
+	var publicKeys stringList
+	fs.Var(&publicKeys, "public-key", "Trusted issuer public key (repeatable)")
+	keyring := fs.String("keyring", "", "File of trusted issuer public keys")
+	revocations := fs.String("revocations", "", "Signed revocation list to check the license against")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *requestPath == "" || *key == "" {
+		fmt.Fprintf(os.Stderr, "Error: --request and --license are required\n")
+		return exitUsage
+	}
+	req, err := license.ReadActivationRequest(*requestPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	trusted, err := loadKeyring(publicKeys, *keyring)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if len(trusted) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --public-key or --keyring is required\n")
+		return exitUsage
+	}
+	verifier := &license.Verifier{Keys: trusted}
+	if *revocations != "" {
+		if verifier.Revoked, err = loadRevocationList(*revocations, trusted); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+	if machine, err := license.MachineFingerprint(); err == nil && machine != req.Machine {
+		fmt.Fprintf(os.Stderr, "Warning: %s was written on another machine\n", *requestPath)
+	}
+
+	lic, err := req.Accept(verifier, *key, time.Now())
+	switch {
+	case errors.Is(err, license.ErrActivationMismatch):
+		fmt.Printf("wrong-activation: %v\n", err)
+		return exitWrongMachine
+	case errors.Is(err, license.ErrWrongMachine):
+		fmt.Printf("wrong-machine: %v\n", err)
+		return exitWrongMachine
+	case err != nil:
+		fmt.Printf("%s: %v\n", verdict(err), err)
+		return 1
+	}
+	fmt.Printf("DRIFTLOCK_LICENSE_KEY=%s\n", lic)
+	fmt.Fprintf(os.Stderr, "\nLicense details:\n")
+	printDetails(lic)
+	fmt.Fprintf(os.Stderr, "\nActivation %s accepted.\n", req.Nonce)
+	return 0
+}
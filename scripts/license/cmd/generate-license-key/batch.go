@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// batchRow is one validated line of the batch CSV, ready to sign.
+type batchRow struct {
+	line     int
+	customer string
+	lic      *license.License
+}
+
+// runBatch issues one v2 license per CSV row (customer,tier,days[,claims])
+// and records each in the issuance ledger. Every row is validated before the first
+// key is signed, so a typo on row 40 does not leave 39 keys half-issued.
+func runBatch(args []string) int {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	input := fs.String("input", "", "CSV with a header row: customer,tier,days[,claims]")
+	output := fs.String("output", "", "CSV to write customer,license rows to (default: stdout)")
+	ledgerPath := fs.String("ledger", "", "Issuance ledger (JSONL) to append to")
+	issuer := fs.String("issuer", defaultIssuer(), "Who is issuing, recorded in the ledger")
+	plan := fs.String("plan", "", "Plan for rows whose claims do not name one ("+strings.Join(license.Plans, ", ")+")")
+	var signingKey signingKeyFlags
+	signingKey.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *input == "" || *ledgerPath == "" {
+		fmt.Fprintf(os.Stderr, "Usage: batch --input <csv> --ledger <jsonl> --keystore <file> [--plan <plan>] [--output <csv>]\n")
+		return exitUsage
+	}
+
+	priv, err := signingKey.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	ledger, err := license.OpenLedger(*ledgerPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	in, err := os.Open(*input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer in.Close()
+	rows, err := readBatchCSV(in, license.KeyID(priv.Public()), *plan, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *input, err)
+		return 1
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer out.Close()
+	}
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"customer", "license"}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, row := range rows {
+		key, err := issueToLedger(ledger, priv, row.lic, "issue", row.customer, *issuer)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: line %d (%s): %v\n", row.line, row.customer, err)
+			return 1
+		}
+		if err := w.Write([]string{row.customer, key}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Issued %d license(s); ledger %s updated\n", len(rows), *ledgerPath)
+	return 0
+}
+
+// readBatchCSV parses and validates every row. Every row is issued as v2 with
+// tenant_id defaulting to the customer, so two customers on the same tier and
+// term still get distinct keys and ledger fingerprints. The optional claims
+// column holds a JSON object of further v2 claims; plan fills in for rows
+// whose claims do not name one.
+func readBatchCSV(r io.Reader, keyID, plan string, now time.Time) ([]batchRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"customer", "tier", "days"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("header is missing %q column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := col[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []batchRow
+	seen := map[string]int{} // fingerprint -> line
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		customer, tier := field(record, "customer"), field(record, "tier")
+		if customer == "" || tier == "" {
+			return nil, fmt.Errorf("line %d: customer and tier are required", line)
+		}
+		days, err := strconv.Atoi(field(record, "days"))
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("line %d: days must be a positive integer", line)
+		}
+		expiry := now.Add(time.Duration(days) * 24 * time.Hour).Unix()
+
+		row := batchRow{line: line, customer: customer}
+		claims := license.Claims{TenantID: customer, Plan: plan}
+		if raw := field(record, "claims"); raw != "" {
+			dec := json.NewDecoder(strings.NewReader(raw))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&claims); err != nil {
+				return nil, fmt.Errorf("line %d: claims: %v", line, err)
+			}
+		}
+		claims.Tier = tier
+		claims.Plan = strings.ToLower(claims.Plan)
+		claims.Features = license.SplitFeatures(strings.Join(claims.Features, ","))
+		claims.IssuedAt = now.Unix()
+		if claims.NotBefore == 0 {
+			claims.NotBefore = now.Unix()
+		}
+		claims.Expiry = expiry
+		if row.lic, err = license.NewV2(claims, keyID); err == nil {
+			err = license.CheckSignerPolicy(row.lic)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		fp := license.Fingerprint(row.lic)
+		if prev, ok := seen[fp]; ok {
+			return nil, fmt.Errorf("line %d: would issue the same license as line %d", line, prev)
+		}
+		seen[fp] = line
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no rows to issue")
+	}
+	return rows, nil
+}
+
+// issueToLedger signs lic and records it under action ("issue", "activate",
+// "renew", "upgrade"). The ledger entry is written before the key is handed
+// out, so every key that leaves the tool is accounted for.
+func issueToLedger(ledger *license.Ledger, priv crypto.Signer, lic *license.License, action, customer, issuer string) (string, error) {
+	key, err := license.Sign(priv, lic)
+	if err != nil {
+		return "", err
+	}
+	if err := recordInLedger(ledger, priv.Public(), lic, action, customer, issuer); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// recordInLedger appends an entry for the already signed lic; signer is the
+// key that just signed it.
+func recordInLedger(ledger *license.Ledger, signer crypto.PublicKey, lic *license.License, action, customer, issuer string) error {
+	_, err := ledger.Append(license.LedgerEntry{
+		Action:      action,
+		Time:        time.Now().UTC().Format(time.RFC3339),
+		Issuer:      issuer,
+		KeyID:       license.KeyID(signer),
+		Fingerprint: license.Fingerprint(lic),
+		Customer:    customer,
+		Tier:        lic.Tier,
+		Expiry:      lic.Expiry,
+		Claims:      lic.Claims,
+	})
+	if err != nil {
+		return fmt.Errorf("append to ledger: %w", err)
+	}
+	return nil
+}
+
+// runLedger handles `ledger verify`.
+func runLedger(args []string) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintf(os.Stderr, "Usage: ledger verify --ledger <jsonl>\n")
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("ledger verify", flag.ContinueOnError)
+	ledgerPath := fs.String("ledger", "", "Issuance ledger (JSONL) to verify")
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if *ledgerPath == "" {
+		fmt.Fprintf(os.Stderr, "Error: --ledger is required\n")
+		return exitUsage
+	}
+	f, err := os.Open(*ledgerPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	last, err := license.VerifyLedger(f)
+	if errors.Is(err, license.ErrLedgerTampered) {
+		fmt.Printf("tampered: %v\n", err)
+		return exitBadSignature
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if last == nil {
+		fmt.Println("ok: empty ledger")
+		return 0
+	}
+	fmt.Printf("ok: %d entries, head %s\n", last.Seq, last.Hash)
+	fmt.Fprintf(os.Stderr, "Record the head hash somewhere else; truncating the newest lines cannot be detected from the file alone.\n")
+	return 0
+}
+
+func defaultIssuer() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+func TestBatchCustomersGetDistinctFingerprints(t *testing.T) {
+	in := "customer,tier,days\nAcme,PRO,30\nGlobex,PRO,30\n"
+	rows, err := readBatchCSV(strings.NewReader(in), "f904b5ea37dda0a7", "radar", time.Unix(1767225600, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	a, b := license.Fingerprint(rows[0].lic), license.Fingerprint(rows[1].lic)
+	if a == b {
+		t.Fatalf("Acme and Globex share fingerprint %s", a)
+	}
+	if got := rows[1].lic.Claims.TenantID; got != "Globex" {
+		t.Errorf("tenant_id = %q, want Globex", got)
+	}
+}
+
+func TestBatchRejectsDuplicateRows(t *testing.T) {
+	in := "customer,tier,days\nAcme,PRO,30\nAcme,PRO,30\n"
+	_, err := readBatchCSV(strings.NewReader(in), "f904b5ea37dda0a7", "radar", time.Unix(1767225600, 0))
+	if err == nil || !strings.Contains(err.Error(), "same license as line 2") {
+		t.Fatalf("err = %v, want a duplicate-row error", err)
+	}
+}
+
+func TestBatchRefusesSingleSignerEnterprise(t *testing.T) {
+	in := "customer,tier,days,claims\nAcme,ENTERPRISE,30,\nGlobex,ENTERPRISE,30,\"{\"\"signers\"\":2}\"\n"
+	_, err := readBatchCSV(strings.NewReader(in), "f904b5ea37dda0a7", "radar", time.Unix(1767225600, 0))
+	if !errors.Is(err, license.ErrInsufficientSignatures) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("err = %v, want line 2 refused for too few signers", err)
+	}
+	in = "customer,tier,days,claims\nGlobex,ENTERPRISE,30,\"{\"\"signers\"\":2}\"\n"
+	if _, err := readBatchCSV(strings.NewReader(in), "f904b5ea37dda0a7", "radar", time.Unix(1767225600, 0)); err != nil {
+		t.Fatalf("2-signer ENTERPRISE row: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// claimsFlags are the flags describing what a newly issued license grants.
+type claimsFlags struct {
+	tier           string
+	days           int
+	tenantID       string
+	plan           string
+	eventsPerMonth int64
+	streamLimit    int64
+	features       string
+	notBefore      string
+	signers        int
+}
+
+func (f *claimsFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.tier, "tier", "EVAL", "License tier (e.g., EVAL, PRO, ENTERPRISE)")
+	fs.IntVar(&f.days, "days", 365, "Number of days until expiry")
+	fs.StringVar(&f.tenantID, "tenant", "", "v2: tenant ID the license is issued to")
+	fs.StringVar(&f.plan, "plan", "", "v2: plan name ("+strings.Join(license.Plans, ", ")+")")
+	fs.Int64Var(&f.eventsPerMonth, "events-per-month", 0, "v2: monthly event quota (0 = unlimited)")
+	fs.Int64Var(&f.streamLimit, "stream-limit", 0, "v2: maximum number of streams (0 = unlimited)")
+	fs.StringVar(&f.features, "features", "", "v2: comma-separated feature flags ("+strings.Join(license.Features, ", ")+")")
+	fs.StringVar(&f.notBefore, "not-before", "", "v2: RFC3339 time the license becomes valid (default: now)")
+	fs.IntVar(&f.signers, "signers", 0, "v2: number of distinct keys that must sign; add the others with cosign")
+}
+
+func (f *claimsFlags) expiry(now time.Time) int64 {
+	return now.Add(time.Duration(f.days) * 24 * time.Hour).Unix()
+}
+
+// claims builds v2 claims issued at now.
+func (f *claimsFlags) claims(now time.Time) (license.Claims, error) {
+	c := license.Claims{
+		TenantID:       f.tenantID,
+		Tier:           f.tier,
+		Plan:           strings.ToLower(f.plan),
+		EventsPerMonth: f.eventsPerMonth,
+		StreamLimit:    f.streamLimit,
+		Features:       license.SplitFeatures(f.features),
+		NotBefore:      now.Unix(),
+		IssuedAt:       now.Unix(),
+		Expiry:         f.expiry(now),
+		Signers:        f.signers,
+	}
+	if f.notBefore != "" {
+		nbf, err := time.Parse(time.RFC3339, f.notBefore)
+		if err != nil {
+			return c, fmt.Errorf("invalid --not-before: %v", err)
+		}
+		c.NotBefore = nbf.Unix()
+	}
+	return c, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// runCosign adds the caller's signature to a multi-signer v2 license. The
+// signatures already on it are checked against --keyring and its details
+// shown first, so a signer never endorses a payload that was altered after
+// the first signature.
+func runCosign(args []string) int {
+	fs := flag.NewFlagSet("cosign", flag.ContinueOnError)
+	key := fs.String("license", "", "Partially signed license to add a signature to")
+	keyring := fs.String("keyring", "", "Trusted keys for checking the existing signatures (required)")
+	ledgerPath := fs.String("ledger", "", "Issuance ledger (JSONL) to record the cosignature in")
+	issuer := fs.String("issuer", defaultIssuer(), "Who is signing, recorded in the ledger")
+	customer := fs.String("customer", "", "Customer name, recorded in the ledger")
+	var signingKey signingKeyFlags
+	signingKey.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *key == "" || *keyring == "" {
+		fmt.Fprintf(os.Stderr, "Usage: cosign --license <key> --keystore <file> --keyring <file> [--ledger <jsonl>]\n")
+		return exitUsage
+	}
+
+	priv, err := signingKey.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	lic, err := license.Parse(*key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	trusted, err := loadKeyring(nil, *keyring)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	// Too few signers is expected here; anything else is not.
+	if err := license.VerifySignature(lic, trusted); err != nil && !errors.Is(err, license.ErrInsufficientSignatures) {
+		fmt.Fprintf(os.Stderr, "Error: existing signatures: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Cosigning:\n")
+	printDetails(lic)
+
+	signed, err := license.Cosign(priv, lic)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *ledgerPath != "" {
+		ledger, err := license.OpenLedger(*ledgerPath)
+		if err == nil {
+			err = recordInLedger(ledger, priv.Public(), lic, "cosign", *customer, *issuer)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Printf("DRIFTLOCK_LICENSE_KEY=%s\n", signed)
+	if have, need := 1+len(lic.Cosignatures), lic.RequiredSigners(); have < need {
+		fmt.Fprintf(os.Stderr, "\n%d of %d signatures; pass it on to the next signer.\n", have, need)
+	}
+	return 0
+}
+
+// parseMinSigners reads TIER=N pairs for --min-signers.
+func parseMinSigners(pairs []string) (map[string]int, error) {
+	policy := map[string]int{}
+	for _, p := range pairs {
+		tier, n, ok := strings.Cut(p, "=")
+		count, err := strconv.Atoi(n)
+		if !ok || tier == "" || err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid --min-signers %q (expected TIER=N)", p)
+		}
+		policy[strings.ToUpper(tier)] = count
+	}
+	return policy, nil
+}
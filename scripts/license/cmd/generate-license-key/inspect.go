@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// inspection is what `inspect` reports about a license. Signature is
+// "not-checked" unless trusted keys were supplied.
+type inspection struct {
+	Format        string          `json:"format"`
+	Tier          string          `json:"tier"`
+	Expires       string          `json:"expires"`
+	DaysRemaining int64           `json:"days_remaining"`
+	KeyID         string          `json:"key_id,omitempty"`
+	Alg           string          `json:"alg"`
+	Fingerprint   string          `json:"fingerprint"`
+	Signature     string          `json:"signature"`
+	Cosigners     []string        `json:"cosigners,omitempty"`
+	Claims        *license.Claims `json:"claims,omitempty"`
+}
+
+// runInspect decodes a license and prints its contents. Unlike verify it
+// reports the claims even when verification fails and does not judge expiry:
+// the signature verdict is just one more field, reported as not checked
+// without --public-key or --keyring.
+func runInspect(args []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	key := fs.String("key", os.Getenv("DRIFTLOCK_LICENSE_KEY"), "License key to inspect (defaults to $DRIFTLOCK_LICENSE_KEY)")
+	var publicKeys stringList
+	fs.Var(&publicKeys, "public-key", "Trusted public key to check the signature against (repeatable)")
+	keyring := fs.String("keyring", "", "File of trusted public keys to check the signature against")
+	asJSON := fs.Bool("json", false, "Print JSON instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		*key = fs.Arg(0)
+	}
+	if *key == "" {
+		fmt.Fprintf(os.Stderr, "Usage: inspect [--json] [--public-key <hex>]... [--keyring <file>] <license>\n")
+		return exitUsage
+	}
+
+	lic, err := license.Parse(*key)
+	if err != nil {
+		fmt.Printf("malformed: %v\n", err)
+		return exitMalformed
+	}
+	in := inspect(lic, time.Now())
+	if len(publicKeys) > 0 || *keyring != "" {
+		trusted, err := loadKeyring(publicKeys, *keyring)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		in.Signature = verdict((&license.Verifier{Keys: trusted}).VerifySignatures(lic))
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(in); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := func(k, v string) { fmt.Fprintf(w, "%s\t%s\n", k, v) }
+	row("Format", in.Format)
+	row("Tier", in.Tier)
+	row("Expires", in.Expires)
+	row("Days remaining", fmt.Sprint(in.DaysRemaining))
+	if in.KeyID != "" {
+		row("Key ID", in.KeyID)
+	}
+	row("Algorithm", in.Alg)
+	if len(in.Cosigners) > 0 {
+		row("Cosigned by", strings.Join(in.Cosigners, ", "))
+	}
+	row("Fingerprint", in.Fingerprint)
+	row("Signature", in.Signature)
+	if c := in.Claims; c != nil {
+		row("Tenant", c.TenantID)
+		row("Plan", c.Plan)
+		row("Events/month", formatLimit(c.EventsPerMonth))
+		row("Streams", formatLimit(c.StreamLimit))
+		row("Features", strings.Join(c.Features, ", "))
+		row("Not before", time.Unix(c.NotBefore, 0).UTC().Format(time.RFC3339))
+		row("Issued at", time.Unix(c.IssuedAt, 0).UTC().Format(time.RFC3339))
+		if c.Machine != "" {
+			row("Machine", c.Machine)
+			row("Activation ID", c.ActivationID)
+		}
+		if c.InstallKey != "" {
+			row("Installation key", c.InstallKey)
+		}
+		if c.Previous != "" {
+			row("Replaces", c.Previous)
+		}
+		if c.Signers > 1 {
+			row("Signers required", fmt.Sprint(c.Signers))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func inspect(lic *license.License, now time.Time) inspection {
+	in := inspection{
+		Format:      "v1",
+		Tier:        lic.Tier,
+		Expires:     time.Unix(lic.Expiry, 0).UTC().Format(time.RFC3339),
+		KeyID:       lic.KeyID,
+		Alg:         lic.Alg,
+		Fingerprint: license.Fingerprint(lic),
+		Signature:   "not-checked",
+		Claims:      lic.Claims,
+	}
+	for _, c := range lic.Cosignatures {
+		in.Cosigners = append(in.Cosigners, c.KeyID)
+	}
+	switch {
+	case lic.Version == 2:
+		in.Format = "v2"
+	case lic.KeyID == "":
+		in.Format = "legacy"
+	}
+	// Whole days, rounded towards the past so an expired license never shows 0.
+	remaining := lic.Expiry - now.Unix()
+	in.DaysRemaining = remaining / 86400
+	if remaining < 0 && remaining%86400 != 0 {
+		in.DaysRemaining--
+	}
+	return in
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"crypto"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// defaultPassphraseEnv is read when no other passphrase source is given.
+const defaultPassphraseEnv = "DRIFTLOCK_KEYSTORE_PASSPHRASE"
+
+// signingKeyFlags are the flags shared by every command that signs.
+type signingKeyFlags struct {
+	privateKey      string
+	privateKeyFile  string
+	keystore        string
+	passphraseEnv   string
+	passphraseStdin bool
+}
+
+func (f *signingKeyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.keystore, "keystore", "", "Passphrase-encrypted keystore holding the signing key")
+	fs.StringVar(&f.privateKeyFile, "private-key-file", "", "File containing the hex private key (unencrypted; prefer --keystore)")
+	fs.StringVar(&f.privateKey, "private-key", "", "Private key in hex, P-256 D or ed25519:<seed> (deprecated: leaks into shell history and ps)")
+	passphraseFlags(fs, &f.passphraseEnv, &f.passphraseStdin)
+}
+
+func passphraseFlags(fs *flag.FlagSet, env *string, stdin *bool) {
+	fs.StringVar(env, "passphrase-env", defaultPassphraseEnv, "Environment variable holding the keystore passphrase")
+	fs.BoolVar(stdin, "passphrase-stdin", false, "Read the keystore passphrase from the first line of stdin")
+}
+
+func (f *signingKeyFlags) set() bool {
+	return f.keystore != "" || f.privateKeyFile != "" || f.privateKey != ""
+}
+
+// load returns the signing key from whichever source was given.
+func (f *signingKeyFlags) load() (crypto.Signer, error) {
+	switch {
+	case f.keystore != "":
+		ks, err := license.ReadKeystore(f.keystore)
+		if err != nil {
+			return nil, err
+		}
+		pass, err := readPassphrase(f.passphraseEnv, f.passphraseStdin)
+		if err != nil {
+			return nil, err
+		}
+		return ks.Unlock(pass)
+	case f.privateKeyFile != "":
+		b, err := os.ReadFile(f.privateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read private key: %w", err)
+		}
+		return license.ParsePrivateKeyHex(string(b))
+	case f.privateKey != "":
+		fmt.Fprintf(os.Stderr, "Warning: --private-key exposes the signing key in shell history and ps output; use --keystore\n")
+		return license.ParsePrivateKeyHex(f.privateKey)
+	default:
+		return nil, errors.New("--keystore, --private-key-file or --private-key is required")
+	}
+}
+
+// readPassphrase takes the passphrase from stdin when asked, otherwise from
+// the named environment variable.
+func readPassphrase(env string, fromStdin bool) ([]byte, error) {
+	if fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("read passphrase from stdin: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return nil, errors.New("empty passphrase on stdin")
+		}
+		return []byte(line), nil
+	}
+	if v := os.Getenv(env); v != "" {
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("no keystore passphrase: set $%s or use --passphrase-stdin", env)
+}
+
+// runKeystore handles `keystore create|import|unlock`.
+func runKeystore(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: keystore create --out <file> [--alg p256|ed25519] | import --private-key-file <file> --out <file> | unlock --keystore <file>\n")
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("keystore "+args[0], flag.ContinueOnError)
+	out := fs.String("out", "", "Keystore file to write")
+	keystorePath := fs.String("keystore", "", "Keystore file to unlock")
+	privateKeyFile := fs.String("private-key-file", "", "Hex private key to import")
+	alg := fs.String("alg", license.AlgP256, "Algorithm for a newly created key: p256 or ed25519")
+	var passEnv string
+	var passStdin bool
+	passphraseFlags(fs, &passEnv, &passStdin)
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "create", "import":
+		if *out == "" {
+			fmt.Fprintf(os.Stderr, "Error: --out is required\n")
+			return exitUsage
+		}
+		var priv crypto.Signer
+		var err error
+		if args[0] == "import" {
+			if *privateKeyFile == "" {
+				fmt.Fprintf(os.Stderr, "Error: --private-key-file is required\n")
+				return exitUsage
+			}
+			priv, err = (&signingKeyFlags{privateKeyFile: *privateKeyFile}).load()
+		} else {
+			priv, err = license.GenerateKey(*alg)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := writeKeystore(*out, priv, passEnv, passStdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("KEY_ID=%s\n", license.KeyID(priv.Public()))
+		fmt.Fprintf(os.Stderr, "\nWrote %s\n", *out)
+		fmt.Fprintf(os.Stderr, "  Public key: %s\n", license.PublicKeyHex(priv.Public()))
+		if args[0] == "import" {
+			fmt.Fprintf(os.Stderr, "\nThe keystore does not replace %s; shred it once you have checked the keystore unlocks.\n", *privateKeyFile)
+		}
+		return 0
+	case "unlock":
+		if *keystorePath == "" {
+			fmt.Fprintf(os.Stderr, "Error: --keystore is required\n")
+			return exitUsage
+		}
+		sk := signingKeyFlags{keystore: *keystorePath, passphraseEnv: passEnv, passphraseStdin: passStdin}
+		priv, err := sk.load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("KEY_ID=%s\n", license.KeyID(priv.Public()))
+		fmt.Fprintf(os.Stderr, "Keystore unlocked\n")
+		fmt.Fprintf(os.Stderr, "  Public key: %s\n", license.PublicKeyHex(priv.Public()))
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown keystore command %q\n", args[0])
+		return exitUsage
+	}
+}
+
+func writeKeystore(path string, priv crypto.Signer, passEnv string, passStdin bool) error {
+	pass, err := readPassphrase(passEnv, passStdin)
+	if err != nil {
+		return err
+	}
+	ks, err := license.EncryptKeystore(priv, pass)
+	if err != nil {
+		return err
+	}
+	return license.WriteKeystore(path, ks)
+}
@@ -0,0 +1,441 @@
+// Command generate-license-key issues and checks DRIFTLOCK_LICENSE_KEY values.
+// It is a thin wrapper over the license package; run it from scripts/license:
+//
+//	go run ./cmd/generate-license-key keygen --out signing --encrypt
+//	go run ./cmd/generate-license-key --keystore signing.keystore --tier PRO
+//	go run ./cmd/generate-license-key verify --key <license> --keyring trusted.pub
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// Exit codes for the verify subcommand, so scripts can branch on the outcome.
+const (
+	exitValid        = 0
+	exitUsage        = 2
+	exitExpired      = 3
+	exitBadSignature = 4
+	exitMalformed    = 5
+	exitUnknownKey   = 6
+	exitNotYetValid  = 7
+	exitRevoked      = 8
+	exitWrongMachine = 9
+	exitNeedsSigners = 10
+	exitGrace        = 11
+	exitOverQuota    = 12
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			os.Exit(runVerify(os.Args[2:]))
+		case "keygen":
+			os.Exit(runKeygen(os.Args[2:]))
+		case "keystore":
+			os.Exit(runKeystore(os.Args[2:]))
+		case "revoke":
+			os.Exit(runRevoke(os.Args[2:]))
+		case "batch":
+			os.Exit(runBatch(os.Args[2:]))
+		case "ledger":
+			os.Exit(runLedger(os.Args[2:]))
+		case "activate":
+			os.Exit(runActivate(os.Args[2:]))
+		case "inspect":
+			os.Exit(runInspect(os.Args[2:]))
+		case "renew", "upgrade":
+			os.Exit(runRenew(os.Args[1], os.Args[2:]))
+		case "cosign":
+			os.Exit(runCosign(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "usage":
+			os.Exit(runUsage(os.Args[2:]))
+		case "vectors":
+			os.Exit(runVectors(os.Args[2:]))
+		}
+	}
+
+	var grant claimsFlags
+	grant.register(flag.CommandLine)
+	var signingKey signingKeyFlags
+	signingKey.register(flag.CommandLine)
+	legacy := flag.Bool("legacy", false, "Emit the legacy TIER.EXPIRY.SIG format without a key ID")
+	format := flag.String("format", "v1", "License format: v1 (TIER.EXPIRY.KEYID.SIG) or v2 (signed JSON claims)")
+	ledgerPath := flag.String("ledger", "", "Issuance ledger (JSONL) to record the new license in")
+	issuer := flag.String("issuer", defaultIssuer(), "Who is issuing, recorded in the ledger")
+	customer := flag.String("customer", "", "Customer name, recorded in the ledger")
+	flag.Parse()
+
+	if !signingKey.set() {
+		fmt.Fprintf(os.Stderr, "Error: --keystore is required\n")
+		fmt.Fprintf(os.Stderr, "\nUsage: go run ./cmd/generate-license-key --keystore <file> [--tier EVAL] [--days 365] [--legacy]\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key --keystore <file> --format v2 --tenant <id> --plan <plan> [--features openzl,...]\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key verify [--key <license>] [--public-key <hex>]... [--keyring <file>] [--revocations <file>] [--machine <fingerprint>]\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key inspect [--json] [--keyring <file>] <license>\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key renew|upgrade --license <key> --keystore <file> [--days N] [--tier <tier>]\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key cosign --license <key> --keystore <file> --keyring <file>\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key serve --token-file <file> --ledger <jsonl> --keystore <file>\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key usage report|reconcile ...\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key vectors [--out <file>] | --check <file>\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key keygen --out <prefix> [--alg p256|ed25519] [--encrypt]\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key keystore create|import|unlock ...\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key revoke --list <file> --keystore <file> --license <key> --reason <text>\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key batch --input <csv> --ledger <jsonl> --keystore <file> [--plan <plan>]\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key ledger verify --ledger <jsonl>\n")
+		fmt.Fprintf(os.Stderr, "       go run ./cmd/generate-license-key activate request --out <file> | issue --request <file> --keystore <file> | accept --request <file> --license <key> --keyring <file>\n")
+		fmt.Fprintf(os.Stderr, "\nThe keystore passphrase is read from $%s or, with --passphrase-stdin, stdin.\n", defaultPassphraseEnv)
+		fmt.Fprintf(os.Stderr, "\nIf you don't have a signing key, create one with keygen and add the\n")
+		fmt.Fprintf(os.Stderr, "printed public key to the verifier's trusted keyring. Licenses signed by\n")
+		fmt.Fprintf(os.Stderr, "older keys stay valid as long as their public keys remain trusted.\n")
+		os.Exit(1)
+	}
+
+	priv, err := signingKey.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	keyID := license.KeyID(priv.Public())
+
+	if *legacy && *format != "v1" {
+		fmt.Fprintf(os.Stderr, "Error: --legacy is only supported with --format v1\n")
+		os.Exit(1)
+	}
+	// Legacy licenses carry no key ID, so verifiers can only check them
+	// against the original license.go public key.
+	if *legacy && license.PublicKeyHex(priv.Public()) != license.LegacyPublicKeyHex {
+		fmt.Fprintf(os.Stderr, "Error: --legacy requires the private key matching the public key in license.go\n")
+		fmt.Fprintf(os.Stderr, "  Expected public key: %s\n", license.LegacyPublicKeyHex)
+		fmt.Fprintf(os.Stderr, "  Generated public key: %s\n", license.PublicKeyHex(priv.Public()))
+		os.Exit(1)
+	}
+
+	// Generate license key
+	now := time.Now()
+	var lic *license.License
+	switch {
+	case *format == "v2":
+		claims, err := grant.claims(now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		lic, err = license.NewV2(claims, keyID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case *format != "v1":
+		fmt.Fprintf(os.Stderr, "Error: unknown --format %q (expected v1 or v2)\n", *format)
+		os.Exit(1)
+	case *legacy:
+		lic = license.NewV1(grant.tier, grant.expiry(now), "")
+	default:
+		lic = license.NewV1(grant.tier, grant.expiry(now), keyID)
+	}
+	if err := license.CheckSignerPolicy(lic); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v; issue with --format v2 --signers %d and add the others with cosign\n", err, license.DefaultMinSigners[strings.ToUpper(lic.Tier)])
+		os.Exit(1)
+	}
+
+	var key string
+	if *ledgerPath != "" {
+		ledger, err := license.OpenLedger(*ledgerPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		key, err = issueToLedger(ledger, priv, lic, "issue", *customer, *issuer)
+	} else {
+		key, err = license.Sign(priv, lic)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("DRIFTLOCK_LICENSE_KEY=%s\n", key)
+	fmt.Fprintf(os.Stderr, "\nLicense details:\n")
+	printDetails(lic)
+	if n := lic.RequiredSigners(); n > 1 {
+		fmt.Fprintf(os.Stderr, "\nThis license needs %d more signature(s); pass it to the other signers:\n", n-1)
+		fmt.Fprintf(os.Stderr, "  go run ./cmd/generate-license-key cosign --keystore <file> --license %s\n", key)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nExport it:\n")
+	fmt.Fprintf(os.Stderr, "  export DRIFTLOCK_LICENSE_KEY=%s\n", key)
+}
+
+// runKeygen writes a fresh P-256 or Ed25519 keypair as <prefix>.key (private
+// key, hex) or, with --encrypt, <prefix>.keystore, plus <prefix>.pub (public
+// key, hex), and prints its key ID.
+func runKeygen(args []string) int {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	out := fs.String("out", "", "Output path prefix; writes <prefix>.key (or .keystore) and <prefix>.pub")
+	alg := fs.String("alg", license.AlgP256, "Signing algorithm: p256 or ed25519 (deterministic signatures, shorter keys)")
+	encrypt := fs.Bool("encrypt", false, "Write a passphrase-encrypted <prefix>.keystore instead of a plain hex key")
+	var passEnv string
+	var passStdin bool
+	passphraseFlags(fs, &passEnv, &passStdin)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *out == "" {
+		fmt.Fprintf(os.Stderr, "Error: --out is required\n")
+		return exitUsage
+	}
+
+	priv, err := license.GenerateKey(*alg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: generate key: %v\n", err)
+		return 1
+	}
+	privPath, pubPath := *out+".key", *out+".pub"
+	if *encrypt {
+		privPath = *out + ".keystore"
+	}
+	if _, err := os.Stat(privPath); err == nil {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists; refusing to overwrite a signing key\n", privPath)
+		return 1
+	}
+	if *encrypt {
+		err = writeKeystore(privPath, priv, passEnv, passStdin)
+	} else {
+		err = os.WriteFile(privPath, []byte(license.PrivateKeyHex(priv)+"\n"), 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write private key: %v\n", err)
+		return 1
+	}
+	pubHex := license.PublicKeyHex(priv.Public())
+	if err := os.WriteFile(pubPath, []byte(pubHex+"\n"), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: write public key: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("KEY_ID=%s\n", license.KeyID(priv.Public()))
+	fmt.Fprintf(os.Stderr, "\nWrote %s (keep secret) and %s\n", privPath, pubPath)
+	fmt.Fprintf(os.Stderr, "  Public key: %s\n", pubHex)
+	fmt.Fprintf(os.Stderr, "\nAdd the public key to every verifier's keyring before issuing with it.\n")
+	return 0
+}
+
+// runVerify checks an existing license key against the trusted public keys and
+// returns the process exit code describing the verdict.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	key := fs.String("key", os.Getenv("DRIFTLOCK_LICENSE_KEY"), "License key to verify (defaults to $DRIFTLOCK_LICENSE_KEY)")
+	var publicKeys stringList
+	fs.Var(&publicKeys, "public-key", "Trusted public key: P-256 uncompressed hex or ed25519:<hex> (repeatable)")
+	keyring := fs.String("keyring", "", "File of trusted public keys, one hex key per line (# comments allowed)")
+	revocations := fs.String("revocations", "", "Signed revocation list to check the license against")
+	machine := fs.String("machine", "", "Machine fingerprint for host-bound licenses (default: this machine's)")
+	var minSigners stringList
+	fs.Var(&minSigners, "min-signers", "TIER=N: require N trusted signers for v2 TIER licenses (repeatable; default ENTERPRISE=2)")
+	graceDays := fs.Int("grace-days", 0, "Days after expiry to report grace (exit 11) instead of expired")
+	clockSkew := fs.Duration("clock-skew", 5*time.Minute, "How far in the future a not-before time may be and still be accepted")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *key == "" {
+		fmt.Fprintf(os.Stderr, "Error: --key or DRIFTLOCK_LICENSE_KEY is required\n")
+		return exitUsage
+	}
+
+	trusted, err := loadKeyring(publicKeys, *keyring)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if len(trusted) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --public-key or --keyring is required\n")
+		return exitUsage
+	}
+
+	if *graceDays < 0 || *clockSkew < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --grace-days and --clock-skew must not be negative\n")
+		return exitUsage
+	}
+	verifier := &license.Verifier{
+		Keys:      trusted,
+		Machine:   *machine,
+		ClockSkew: *clockSkew,
+		Grace:     time.Duration(*graceDays) * 24 * time.Hour,
+	}
+	if len(minSigners) > 0 {
+		if verifier.MinSigners, err = parseMinSigners(minSigners); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+	if verifier.Machine == "" {
+		// Unbound licenses verify without it, so a missing machine ID is
+		// only reported if the license turns out to be host-bound.
+		verifier.Machine, _ = license.MachineFingerprint()
+	}
+	if *revocations != "" {
+		if verifier.Revoked, err = loadRevocationList(*revocations, trusted); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	now := time.Now()
+	res := verifier.Check(*key, now)
+	lic, err := res.License, res.Err
+	if lic != nil {
+		fmt.Fprintf(os.Stderr, "License details:\n")
+		printDetails(lic)
+	}
+	switch {
+	case res.Status == license.StatusValid:
+		fmt.Println("valid")
+		return exitValid
+	case res.Status == license.StatusGrace:
+		left := res.GraceEnds.Sub(now)
+		fmt.Printf("grace: expired %s, grace period ends %s\n",
+			time.Unix(lic.Expiry, 0).UTC().Format(time.RFC3339), res.GraceEnds.UTC().Format(time.RFC3339))
+		fmt.Fprintf(os.Stderr, "Warning: this license has expired; it stops working in %d day(s). Renew it now.\n", int(left.Hours()/24)+1)
+		return exitGrace
+	case errors.Is(err, license.ErrMalformed):
+		fmt.Printf("malformed: %v\n", err)
+		return exitMalformed
+	case errors.Is(err, license.ErrUnknownKey):
+		fmt.Printf("unknown-key: %s\n", lic.KeyID)
+		return exitUnknownKey
+	case errors.Is(err, license.ErrBadSignature):
+		fmt.Println("bad-signature")
+		return exitBadSignature
+	case errors.Is(err, license.ErrInsufficientSignatures):
+		fmt.Printf("needs-signers: %v\n", err)
+		return exitNeedsSigners
+	case errors.Is(err, license.ErrRevoked):
+		fmt.Printf("revoked: %v\n", err)
+		return exitRevoked
+	case errors.Is(err, license.ErrWrongMachine):
+		fmt.Printf("wrong-machine: %v\n", err)
+		return exitWrongMachine
+	case errors.Is(err, license.ErrNotYetValid):
+		fmt.Println("not-yet-valid")
+		return exitNotYetValid
+	case errors.Is(err, license.ErrExpired):
+		fmt.Println("expired")
+		return exitExpired
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+}
+
+// verdict names a verification outcome in the words verify prints.
+func verdict(err error) string {
+	switch {
+	case err == nil:
+		return "valid"
+	case errors.Is(err, license.ErrMalformed):
+		return "malformed"
+	case errors.Is(err, license.ErrUnknownKey):
+		return "unknown-key"
+	case errors.Is(err, license.ErrBadSignature):
+		return "bad-signature"
+	case errors.Is(err, license.ErrInsufficientSignatures):
+		return "needs-signers"
+	case errors.Is(err, license.ErrRevoked):
+		return "revoked"
+	case errors.Is(err, license.ErrWrongMachine):
+		return "wrong-machine"
+	case errors.Is(err, license.ErrNotYetValid):
+		return "not-yet-valid"
+	case errors.Is(err, license.ErrExpired):
+		return "expired"
+	default:
+		return "error"
+	}
+}
+
+// loadKeyring builds the verifier keyring from --public-key values and an
+// optional keyring file. There is no fallback: the original license.go key is
+// the P-256 base point, whose private key is 1, so it is only trusted when
+// named explicitly.
+func loadKeyring(publicKeys []string, keyringPath string) (license.Keyring, error) {
+	trusted := license.Keyring{}
+	if keyringPath != "" {
+		f, err := os.Open(keyringPath)
+		if err != nil {
+			return nil, fmt.Errorf("open keyring: %w", err)
+		}
+		defer f.Close()
+		if trusted, err = license.ReadKeyring(f); err != nil {
+			return nil, err
+		}
+	}
+	for _, h := range publicKeys {
+		pub, err := license.ParsePublicKeyHex(h)
+		if err != nil {
+			return nil, err
+		}
+		trusted.Add(pub)
+	}
+	return trusted, nil
+}
+
+func printDetails(lic *license.License) {
+	fmt.Fprintf(os.Stderr, "  Tier: %s\n", lic.Tier)
+	fmt.Fprintf(os.Stderr, "  Expires: %s\n", time.Unix(lic.Expiry, 0).UTC().Format(time.RFC3339))
+	if lic.KeyID != "" {
+		fmt.Fprintf(os.Stderr, "  Key ID: %s (%s)\n", lic.KeyID, lic.Alg)
+	}
+	for _, c := range lic.Cosignatures {
+		fmt.Fprintf(os.Stderr, "  Cosigned by: %s (%s)\n", c.KeyID, c.Alg)
+	}
+	if c := lic.Claims; c != nil {
+		fmt.Fprintf(os.Stderr, "  Tenant: %s\n", c.TenantID)
+		fmt.Fprintf(os.Stderr, "  Plan: %s\n", c.Plan)
+		fmt.Fprintf(os.Stderr, "  Events/month: %s\n", formatLimit(c.EventsPerMonth))
+		fmt.Fprintf(os.Stderr, "  Streams: %s\n", formatLimit(c.StreamLimit))
+		fmt.Fprintf(os.Stderr, "  Features: %s\n", strings.Join(c.Features, ", "))
+		fmt.Fprintf(os.Stderr, "  Not before: %s\n", time.Unix(c.NotBefore, 0).UTC().Format(time.RFC3339))
+		fmt.Fprintf(os.Stderr, "  Issued at: %s\n", time.Unix(c.IssuedAt, 0).UTC().Format(time.RFC3339))
+		if c.Machine != "" {
+			fmt.Fprintf(os.Stderr, "  Machine: %s\n", c.Machine)
+			fmt.Fprintf(os.Stderr, "  Activation ID: %s\n", c.ActivationID)
+		}
+		if c.InstallKey != "" {
+			fmt.Fprintf(os.Stderr, "  Installation key: %s\n", c.InstallKey)
+		}
+		if c.Previous != "" {
+			fmt.Fprintf(os.Stderr, "  Replaces: %s\n", c.Previous)
+		}
+		if c.Signers > 1 {
+			fmt.Fprintf(os.Stderr, "  Signers required: %d\n", c.Signers)
+		}
+	}
+}
+
+func formatLimit(n int64) string {
+	if n == 0 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// runRenew handles `renew` and `upgrade`. Both reissue an existing license
+// with its claims intact and the old license's fingerprint recorded as
+// claims.previous, so a chain of renewals can be walked back to the first
+// key. renew extends the expiry by --days (counted from the old expiry, or
+// from now if it has lapsed); upgrade changes the tier and keeps the expiry
+// unless --days is given.
+func runRenew(cmd string, args []string) int {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	key := fs.String("license", "", "License to renew or upgrade")
+	keyring := fs.String("keyring", "", "Extra trusted keys for checking the license being replaced")
+	revocations := fs.String("revocations", "", "Signed revocation list; revoked licenses are not renewed")
+	tier := fs.String("tier", "", "New tier (required for upgrade)")
+	plan := fs.String("plan", "", "New plan (default: keep)")
+	defaultDays := 365
+	if cmd == "upgrade" {
+		defaultDays = 0
+	}
+	days := fs.Int("days", defaultDays, "Days to extend the expiry by (0 keeps it)")
+	signers := fs.Int("signers", 0, "Number of distinct keys that must sign the new license (0 keeps it)")
+	tenantID := fs.String("tenant", "", "Tenant ID, required when replacing a v1 license (which has no claims)")
+	ledgerPath := fs.String("ledger", "", "Issuance ledger (JSONL) to record the new license in")
+	issuer := fs.String("issuer", defaultIssuer(), "Who is issuing, recorded in the ledger")
+	customer := fs.String("customer", "", "Customer name, recorded in the ledger")
+	var signingKey signingKeyFlags
+	signingKey.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *key == "" || (cmd == "upgrade" && *tier == "") {
+		fmt.Fprintf(os.Stderr, "Usage: renew --license <key> --keystore <file> [--days 365] [--revocations <file>]\n")
+		fmt.Fprintf(os.Stderr, "       upgrade --license <key> --keystore <file> --tier <tier> [--plan <plan>] [--days 0] [--revocations <file>]\n")
+		return exitUsage
+	}
+
+	priv, err := signingKey.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	trusted, err := loadKeyring(nil, *keyring)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	trusted.Add(priv.Public())
+	prev, err := license.Parse(*key)
+	if err == nil {
+		err = license.VerifySignature(prev, trusted)
+	}
+	if err == nil && *revocations != "" {
+		var rl *license.RevocationList
+		if rl, err = loadRevocationList(*revocations, trusted); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		err = rl.Check(prev)
+	}
+	if errors.Is(err, license.ErrRevoked) {
+		fmt.Fprintf(os.Stderr, "Error: license being replaced: %v\n", err)
+		return exitRevoked
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: license being replaced: %v\n", err)
+		return 1
+	}
+
+	change := renewal{upgrade: cmd == "upgrade", tier: *tier, plan: *plan, days: *days, signers: *signers, tenantID: *tenantID}
+	claims, err := change.claims(prev, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	lic, err := license.NewV2(claims, license.KeyID(priv.Public()))
+	if err == nil {
+		err = license.CheckSignerPolicy(lic)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var newKey string
+	if *ledgerPath != "" {
+		ledger, err := license.OpenLedger(*ledgerPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		newKey, err = issueToLedger(ledger, priv, lic, cmd, *customer, *issuer)
+	} else {
+		newKey, err = license.Sign(priv, lic)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("DRIFTLOCK_LICENSE_KEY=%s\n", newKey)
+	fmt.Fprintf(os.Stderr, "\nLicense details:\n")
+	printDetails(lic)
+	if n := lic.RequiredSigners(); n > 1 {
+		fmt.Fprintf(os.Stderr, "\nThis license needs %d more signature(s); pass it to the other signers for cosign.\n", n-1)
+	}
+	fmt.Fprintf(os.Stderr, "\nThe previous license stays valid until it expires; to retire it now run\n")
+	fmt.Fprintf(os.Stderr, "  revoke --fingerprint %s --reason superseded ...\n", claims.Previous)
+	return 0
+}
+
+// renewal describes how a replacement license differs from the one it
+// replaces. Zero values keep the old license's settings.
+type renewal struct {
+	upgrade  bool
+	tier     string
+	plan     string
+	days     int
+	signers  int
+	tenantID string // only for replacing v1 licenses, which have no claims
+}
+
+// claims returns the claims of the license replacing prev, which must
+// already have been verified.
+func (r renewal) claims(prev *license.License, now time.Time) (license.Claims, error) {
+	var claims license.Claims
+	if prev.Claims != nil {
+		claims = *prev.Claims
+	} else {
+		if r.tenantID == "" || r.plan == "" {
+			return claims, fmt.Errorf("%s license has no claims; a tenant and plan are required to reissue it as v2", prev.Tier)
+		}
+		claims = license.Claims{TenantID: r.tenantID, Tier: prev.Tier, Features: []string{}, Expiry: prev.Expiry}
+	}
+	if r.upgrade && (r.tier == "" || strings.EqualFold(r.tier, claims.Tier)) {
+		return claims, fmt.Errorf("license is already %s", claims.Tier)
+	}
+	if r.tier != "" {
+		claims.Tier = r.tier
+	}
+	if r.plan != "" {
+		claims.Plan = strings.ToLower(r.plan)
+	}
+	if r.days < 0 {
+		return claims, fmt.Errorf("days must not be negative")
+	}
+	if r.days > 0 {
+		from := claims.Expiry
+		if from < now.Unix() {
+			from = now.Unix()
+		}
+		claims.Expiry = from + int64(r.days)*24*60*60
+	}
+	if r.signers > 0 {
+		claims.Signers = r.signers
+	}
+	claims.NotBefore = now.Unix()
+	claims.IssuedAt = now.Unix()
+	claims.Previous = license.Fingerprint(prev)
+	return claims, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// runRevoke adds a license (or bare fingerprint) to a signed revocation list,
+// creating the list if it does not exist, and re-signs it.
+func runRevoke(args []string) int {
+	fs := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	listPath := fs.String("list", "", "Revocation list file to create or update")
+	key := fs.String("license", "", "License key to revoke")
+	fingerprint := fs.String("fingerprint", "", "Fingerprint to revoke when the license itself is not at hand")
+	reason := fs.String("reason", "", "Why the license is revoked (e.g. refund, key leaked)")
+	var signingKey signingKeyFlags
+	signingKey.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *listPath == "" || *reason == "" || (*key == "") == (*fingerprint == "") {
+		fmt.Fprintf(os.Stderr, "Usage: revoke --list <file> --keystore <file> (--license <key> | --fingerprint <hex>) --reason <text>\n")
+		return exitUsage
+	}
+
+	fp := strings.ToLower(*fingerprint)
+	if b, err := hex.DecodeString(fp); *key == "" && (err != nil || len(b) != 32) {
+		fmt.Fprintf(os.Stderr, "Error: --fingerprint must be 64 hex characters\n")
+		return exitUsage
+	}
+	if *key != "" {
+		lic, err := license.Parse(*key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitMalformed
+		}
+		fp = license.Fingerprint(lic)
+	}
+
+	priv, err := signingKey.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	rl, err := revokeInList(*listPath, priv, fp, *reason, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("REVOKED=%s\n", fp)
+	fmt.Fprintf(os.Stderr, "\nUpdated %s (%d revoked license(s))\n", *listPath, len(rl.Entries))
+	fmt.Fprintf(os.Stderr, "Ship this file to offline installations alongside the trusted keyring.\n")
+	return 0
+}
+
+// revokeInList adds fp to the revocation list at path, creating it if need
+// be, and re-signs it with priv.
+func revokeInList(path string, priv crypto.Signer, fp, reason string, now time.Time) (*license.RevocationList, error) {
+	rl := &license.RevocationList{}
+	if _, err := os.Stat(path); err == nil {
+		if rl, err = license.ReadRevocationList(path); err != nil {
+			return nil, err
+		}
+		// Refuse to extend a list we cannot vouch for; re-signing it would
+		// launder whatever was edited into it.
+		if err := rl.Verify(license.NewKeyring(priv.Public())); err != nil {
+			return nil, fmt.Errorf("existing list does not verify with this signing key: %w", err)
+		}
+	}
+	rl.Revoke(fp, reason, now.Unix())
+	if err := license.SignRevocationList(priv, rl, now.Unix()); err != nil {
+		return nil, err
+	}
+	if err := license.WriteRevocationList(path, rl); err != nil {
+		return nil, fmt.Errorf("write revocation list: %w", err)
+	}
+	return rl, nil
+}
+
+// loadRevocationList reads and verifies a revocation list against keys.
+func loadRevocationList(path string, keys license.Keyring) (*license.RevocationList, error) {
+	rl, err := license.ReadRevocationList(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := rl.Verify(keys); err != nil {
+		return nil, fmt.Errorf("revocation list %s: %w", path, err)
+	}
+	return rl, nil
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// maxRequestBody bounds every request body; licenses are a few hundred bytes.
+const maxRequestBody = 64 << 10
+
+// server exposes issue, inspect, renew and revoke over HTTP for billing
+// webhooks. Every call must carry the bearer token, and every authenticated
+// call is appended to the issuance ledger, inspections and refusals
+// included, so the ledger shows who asked about which license and when.
+// Calls without the token only bump authFailures: anyone who can reach the
+// port could otherwise grow the ledger without limit.
+type server struct {
+	priv        crypto.Signer
+	keys        license.Keyring
+	token       string
+	issuer      string
+	revocations string
+	now         func() time.Time
+
+	mu     sync.Mutex // serialises ledger and revocation list writes
+	ledger *license.Ledger
+
+	authFailures atomic.Int64
+}
+
+// newServer returns a server signing with priv. keys are trusted for
+// licenses presented to inspect, renew and revoke, in addition to priv's
+// own public key. An empty revocations path disables the revoke endpoint.
+func newServer(priv crypto.Signer, keys license.Keyring, ledger *license.Ledger, token, issuer, revocations string) *server {
+	trusted := license.NewKeyring(priv.Public())
+	for id, pub := range keys {
+		trusted[id] = pub
+	}
+	return &server{
+		priv:        priv,
+		keys:        trusted,
+		token:       token,
+		issuer:      issuer,
+		revocations: revocations,
+		now:         time.Now,
+		ledger:      ledger,
+	}
+}
+
+// handler returns the routes, all behind bearer-token authentication.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/licenses/issue", s.handleIssue)
+	mux.HandleFunc("POST /v1/licenses/inspect", s.handleInspect)
+	mux.HandleFunc("POST /v1/licenses/renew", s.handleRenew)
+	mux.HandleFunc("POST /v1/licenses/revoke", s.handleRevoke)
+	return s.authenticate(mux)
+}
+
+func (s *server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			// Logged at powers of ten, so a flood cannot fill the log either.
+			if n := s.authFailures.Add(1); isPowerOfTen(n) {
+				fmt.Fprintf(os.Stderr, "Warning: %d unauthenticated request(s) refused, latest from %s\n", n, r.RemoteAddr)
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="driftlock-license"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isPowerOfTen(n int64) bool {
+	for n >= 10 && n%10 == 0 {
+		n /= 10
+	}
+	return n == 1
+}
+
+// issueRequest mirrors the issue flags; Format defaults to v1 as on the
+// command line.
+type issueRequest struct {
+	Customer       string   `json:"customer"`
+	Format         string   `json:"format"`
+	Tier           string   `json:"tier"`
+	Days           int      `json:"days"`
+	TenantID       string   `json:"tenant_id"`
+	Plan           string   `json:"plan"`
+	EventsPerMonth int64    `json:"events_per_month"`
+	StreamLimit    int64    `json:"stream_limit"`
+	Features       []string `json:"features"`
+	NotBefore      string   `json:"not_before"`
+	Signers        int      `json:"signers"`
+}
+
+type licenseRequest struct {
+	License string `json:"license"`
+}
+
+type renewRequest struct {
+	License  string `json:"license"`
+	Customer string `json:"customer"`
+	Tier     string `json:"tier"`
+	Plan     string `json:"plan"`
+	Days     int    `json:"days"`
+	Signers  int    `json:"signers"`
+	TenantID string `json:"tenant_id"`
+}
+
+type revokeRequest struct {
+	License     string `json:"license"`
+	Fingerprint string `json:"fingerprint"`
+	Reason      string `json:"reason"`
+	Customer    string `json:"customer"`
+}
+
+// licenseResponse is returned by issue and renew.
+type licenseResponse struct {
+	License string     `json:"license"`
+	Details inspection `json:"details"`
+}
+
+func (s *server) handleIssue(w http.ResponseWriter, r *http.Request) {
+	var req issueRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	grant := claimsFlags{
+		tier:           req.Tier,
+		days:           req.Days,
+		tenantID:       req.TenantID,
+		plan:           req.Plan,
+		eventsPerMonth: req.EventsPerMonth,
+		streamLimit:    req.StreamLimit,
+		features:       strings.Join(req.Features, ","),
+		notBefore:      req.NotBefore,
+		signers:        req.Signers,
+	}
+	if grant.tier == "" {
+		grant.tier = "EVAL"
+	}
+	if grant.days == 0 {
+		grant.days = 365
+	}
+	if grant.days < 0 {
+		s.reject(w, r, http.StatusBadRequest, errors.New("days must be positive"))
+		return
+	}
+
+	now := s.now()
+	keyID := license.KeyID(s.priv.Public())
+	var lic *license.License
+	switch req.Format {
+	case "", "v1":
+		lic = license.NewV1(grant.tier, grant.expiry(now), keyID)
+	case "v2":
+		claims, err := grant.claims(now)
+		if err == nil {
+			lic, err = license.NewV2(claims, keyID)
+		}
+		if err != nil {
+			s.reject(w, r, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		s.reject(w, r, http.StatusBadRequest, fmt.Errorf("unknown format %q (expected v1 or v2)", req.Format))
+		return
+	}
+	s.sign(w, r, lic, "issue", req.Customer)
+}
+
+func (s *server) handleInspect(w http.ResponseWriter, r *http.Request) {
+	var req licenseRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	lic, err := license.Parse(req.License)
+	if err != nil {
+		s.reject(w, r, http.StatusBadRequest, err)
+		return
+	}
+	in := inspect(lic, s.now())
+	in.Signature = verdict((&license.Verifier{Keys: s.keys}).VerifySignatures(lic))
+
+	s.mu.Lock()
+	err = recordInLedger(s.ledger, s.priv.Public(), lic, "inspect", "", s.issuer)
+	s.mu.Unlock()
+	if err != nil {
+		s.reject(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, in)
+}
+
+// handleRenew covers both renew and upgrade: a request that changes the
+// tier is recorded as an upgrade.
+func (s *server) handleRenew(w http.ResponseWriter, r *http.Request) {
+	var req renewRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	prev, err := s.verified(req.License)
+	if err != nil {
+		s.reject(w, r, verificationStatus(err), err)
+		return
+	}
+	change := renewal{tier: req.Tier, plan: req.Plan, days: req.Days, signers: req.Signers, tenantID: req.TenantID}
+	if change.tier == "" && change.days == 0 {
+		change.days = 365
+	}
+	claims, err := change.claims(prev, s.now())
+	if err != nil {
+		s.reject(w, r, http.StatusBadRequest, err)
+		return
+	}
+	lic, err := license.NewV2(claims, license.KeyID(s.priv.Public()))
+	if err != nil {
+		s.reject(w, r, http.StatusBadRequest, err)
+		return
+	}
+	action := "renew"
+	if !strings.EqualFold(claims.Tier, prev.Tier) {
+		action = "upgrade"
+	}
+	s.sign(w, r, lic, action, req.Customer)
+}
+
+func (s *server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if s.revocations == "" {
+		s.reject(w, r, http.StatusNotImplemented, errors.New("no revocation list configured (start serve with --revocations)"))
+		return
+	}
+	var req revokeRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if req.Reason == "" || (req.License == "") == (req.Fingerprint == "") {
+		s.reject(w, r, http.StatusBadRequest, errors.New("reason and exactly one of license or fingerprint are required"))
+		return
+	}
+	// Fingerprints are written in lower case; anything else would never match.
+	req.Fingerprint = strings.ToLower(req.Fingerprint)
+	entry := license.LedgerEntry{Action: "revoke", Fingerprint: req.Fingerprint, Customer: req.Customer}
+	if req.License != "" {
+		// Signature only: revoking twice just refreshes the entry.
+		lic, err := license.Parse(req.License)
+		if err == nil {
+			err = license.VerifySignature(lic, s.keys)
+		}
+		if err != nil {
+			s.reject(w, r, verificationStatus(err), err)
+			return
+		}
+		entry.Fingerprint = license.Fingerprint(lic)
+		entry.Tier, entry.Expiry, entry.Claims = lic.Tier, lic.Expiry, lic.Claims
+	} else if b, err := hex.DecodeString(req.Fingerprint); err != nil || len(b) != 32 {
+		s.reject(w, r, http.StatusBadRequest, errors.New("fingerprint must be 64 hex characters"))
+		return
+	}
+
+	now := s.now()
+	entry.Time = now.UTC().Format(time.RFC3339)
+	entry.Issuer = s.issuer
+	entry.KeyID = license.KeyID(s.priv.Public())
+
+	// The list is what verifiers enforce, so it is updated first: a ledger
+	// entry must never claim a revocation that did not take effect.
+	s.mu.Lock()
+	rl, err := revokeInList(s.revocations, s.priv, entry.Fingerprint, req.Reason, now)
+	if err == nil {
+		if _, err = s.ledger.Append(entry); err != nil {
+			err = fmt.Errorf("append to ledger: %w", err)
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		s.reject(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"revoked": entry.Fingerprint, "entries": len(rl.Entries)})
+}
+
+// verified parses key and checks it was signed by a trusted key and has not
+// been revoked. Expired licenses are accepted: renewing them is the point.
+func (s *server) verified(key string) (*license.License, error) {
+	lic, err := license.Parse(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := license.VerifySignature(lic, s.keys); err != nil {
+		return nil, err
+	}
+	if s.revocations != "" {
+		if _, err := os.Stat(s.revocations); err == nil {
+			rl, err := loadRevocationList(s.revocations, s.keys)
+			if err != nil {
+				return nil, err
+			}
+			if err := rl.Check(lic); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return lic, nil
+}
+
+// sign issues lic through the ledger and writes it back to the client.
+func (s *server) sign(w http.ResponseWriter, r *http.Request, lic *license.License, action, customer string) {
+	if err := license.CheckSignerPolicy(lic); err != nil {
+		s.reject(w, r, http.StatusBadRequest, err)
+		return
+	}
+	s.mu.Lock()
+	key, err := issueToLedger(s.ledger, s.priv, lic, action, customer, s.issuer)
+	s.mu.Unlock()
+	if err != nil {
+		s.reject(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	details := inspect(lic, s.now())
+	details.Signature = verdict((&license.Verifier{Keys: s.keys}).VerifySignatures(lic))
+	writeJSON(w, http.StatusOK, licenseResponse{License: key, Details: details})
+}
+
+// reject writes an error response and records the refused request in the
+// ledger, so it accounts for every authenticated request, not only the keys
+// handed out.
+func (s *server) reject(w http.ResponseWriter, r *http.Request, status int, err error) {
+	s.mu.Lock()
+	_, lerr := s.ledger.Append(license.LedgerEntry{
+		Action:  "reject",
+		Time:    s.now().UTC().Format(time.RFC3339),
+		Issuer:  s.issuer,
+		KeyID:   license.KeyID(s.priv.Public()),
+		Request: r.Method + " " + r.URL.Path,
+		Status:  status,
+		Error:   err.Error(),
+	})
+	s.mu.Unlock()
+	if lerr != nil {
+		fmt.Fprintf(os.Stderr, "Error: append to ledger: %v\n", lerr)
+	}
+	writeError(w, status, err)
+}
+
+// verificationStatus maps a license verification error to an HTTP status.
+func verificationStatus(err error) int {
+	if errors.Is(err, license.ErrMalformed) {
+		return http.StatusBadRequest
+	}
+	return http.StatusUnprocessableEntity
+}
+
+func (s *server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		s.reject(w, r, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= 500 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// runServe starts the issuance service.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:8787", "Address to listen on")
+	tokenFile := fs.String("token-file", "", "File holding the bearer token clients must send")
+	ledgerPath := fs.String("ledger", "", "Issuance ledger (JSONL) every authenticated request is recorded in")
+	keyring := fs.String("keyring", "", "Extra trusted keys for licenses presented to inspect, renew and revoke")
+	revocations := fs.String("revocations", "", "Revocation list the revoke endpoint updates")
+	issuer := fs.String("issuer", defaultIssuer()+"@serve", "Issuer recorded in the ledger")
+	var signingKey signingKeyFlags
+	signingKey.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *tokenFile == "" || *ledgerPath == "" {
+		fmt.Fprintf(os.Stderr, "Usage: serve --token-file <file> --ledger <jsonl> --keystore <file> [--addr 127.0.0.1:8787] [--revocations <file>]\n")
+		return exitUsage
+	}
+
+	b, err := os.ReadFile(*tokenFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	token := strings.TrimSpace(string(b))
+	if len(token) < 32 {
+		fmt.Fprintf(os.Stderr, "Error: %s: token must be at least 32 characters\n", *tokenFile)
+		return 1
+	}
+	priv, err := signingKey.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var trusted license.Keyring
+	if *keyring != "" {
+		if trusted, err = loadKeyring(nil, *keyring); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+	ledger, err := license.OpenLedger(*ledgerPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           newServer(priv, trusted, ledger, token, *issuer, *revocations).handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "Serving license issuance on http://%s (key %s)\n", *addr, license.KeyID(priv.Public()))
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
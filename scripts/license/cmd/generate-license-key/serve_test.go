@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+const testToken = "0123456789abcdef0123456789abcdef"
+
+type testServer struct {
+	*httptest.Server
+	ledgerPath  string
+	revocations string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	priv, err := license.GenerateKey(license.AlgP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	ts := &testServer{ledgerPath: filepath.Join(dir, "ledger.jsonl"), revocations: filepath.Join(dir, "revoked.json")}
+	ledger, err := license.OpenLedger(ts.ledgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Server = httptest.NewServer(newServer(priv, nil, ledger, testToken, "test@serve", ts.revocations).handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// post sends body to path with the test token and decodes the response
+// into out, if given.
+func (ts *testServer) post(t *testing.T, path, token string, body, out any) int {
+	t.Helper()
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, ts.URL+path, bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s: decode response: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// ledger returns the entries written so far, after checking the chain.
+func (ts *testServer) ledger(t *testing.T) []license.LedgerEntry {
+	t.Helper()
+	b, err := os.ReadFile(ts.ledgerPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := license.VerifyLedger(bytes.NewReader(b)); err != nil {
+		t.Fatalf("ledger: %v", err)
+	}
+	var entries []license.LedgerEntry
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		var e license.LedgerEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (ts *testServer) wantActions(t *testing.T, want ...string) []license.LedgerEntry {
+	t.Helper()
+	entries := ts.ledger(t)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Action)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("ledger actions = %v, want %v", got, want)
+	}
+	return entries
+}
+
+func TestServeRequiresToken(t *testing.T) {
+	ts := newTestServer(t)
+	for _, token := range []string{"", "wrong-token"} {
+		var resp map[string]string
+		if code := ts.post(t, "/v1/licenses/issue", token, issueRequest{Tier: "PRO"}, &resp); code != http.StatusUnauthorized {
+			t.Fatalf("token %q: status %d, want 401", token, code)
+		}
+		if resp["error"] == "" {
+			t.Errorf("token %q: no error message", token)
+		}
+	}
+	ts.wantActions(t)
+}
+
+func TestServeRoundTrip(t *testing.T) {
+	ts := newTestServer(t)
+
+	var issued licenseResponse
+	code := ts.post(t, "/v1/licenses/issue", testToken, issueRequest{
+		Customer: "Acme", Format: "v2", Tier: "EVAL", Days: 30, TenantID: "acme", Plan: "radar",
+	}, &issued)
+	if code != http.StatusOK || issued.Details.Signature != "valid" {
+		t.Fatalf("issue: status %d, signature %q", code, issued.Details.Signature)
+	}
+	entries := ts.wantActions(t, "issue")
+	if entries[0].Customer != "Acme" || entries[0].Fingerprint != issued.Details.Fingerprint {
+		t.Errorf("issue entry = %+v", entries[0])
+	}
+
+	var in inspection
+	if code := ts.post(t, "/v1/licenses/inspect", testToken, licenseRequest{License: issued.License}, &in); code != http.StatusOK {
+		t.Fatalf("inspect: status %d", code)
+	}
+	if in.Signature != "valid" || in.Claims == nil || in.Claims.TenantID != "acme" {
+		t.Errorf("inspect = %+v", in)
+	}
+	entries = ts.wantActions(t, "issue", "inspect")
+	if entries[1].Fingerprint != issued.Details.Fingerprint {
+		t.Errorf("inspect entry fingerprint = %s", entries[1].Fingerprint)
+	}
+
+	var renewed licenseResponse
+	code = ts.post(t, "/v1/licenses/renew", testToken, renewRequest{License: issued.License, Customer: "Acme", Tier: "PRO"}, &renewed)
+	if code != http.StatusOK {
+		t.Fatalf("renew: status %d", code)
+	}
+	if c := renewed.Details.Claims; c == nil || c.Tier != "PRO" || c.Previous != issued.Details.Fingerprint {
+		t.Errorf("renewed claims = %+v", c)
+	}
+	entries = ts.wantActions(t, "issue", "inspect", "upgrade")
+	if entries[2].Fingerprint != renewed.Details.Fingerprint || entries[2].Tier != "PRO" {
+		t.Errorf("upgrade entry = %+v", entries[2])
+	}
+
+	var revoked map[string]any
+	code = ts.post(t, "/v1/licenses/revoke", testToken, revokeRequest{License: issued.License, Reason: "superseded"}, &revoked)
+	if code != http.StatusOK || revoked["revoked"] != issued.Details.Fingerprint {
+		t.Fatalf("revoke: status %d, %v", code, revoked)
+	}
+	entries = ts.wantActions(t, "issue", "inspect", "upgrade", "revoke")
+	if entries[3].Fingerprint != issued.Details.Fingerprint {
+		t.Errorf("revoke entry fingerprint = %s", entries[3].Fingerprint)
+	}
+	rl, err := license.ReadRevocationList(ts.revocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rl.Lookup(issued.Details.Fingerprint); !ok {
+		t.Errorf("revocation list does not hold %s", issued.Details.Fingerprint)
+	}
+}
+
+func TestServeRefusesRevokedLicense(t *testing.T) {
+	ts := newTestServer(t)
+	var issued licenseResponse
+	if code := ts.post(t, "/v1/licenses/issue", testToken, issueRequest{Tier: "PRO"}, &issued); code != http.StatusOK {
+		t.Fatalf("issue: status %d", code)
+	}
+	if code := ts.post(t, "/v1/licenses/revoke", testToken, revokeRequest{License: issued.License, Reason: "refund"}, nil); code != http.StatusOK {
+		t.Fatalf("revoke: status %d", code)
+	}
+
+	var resp map[string]string
+	code := ts.post(t, "/v1/licenses/renew", testToken, renewRequest{License: issued.License, TenantID: "acme", Plan: "radar"}, &resp)
+	if code != http.StatusUnprocessableEntity || !strings.Contains(resp["error"], "revoked") {
+		t.Fatalf("renew revoked: status %d, %v", code, resp)
+	}
+	entries := ts.wantActions(t, "issue", "revoke", "reject")
+	if e := entries[2]; e.Status != http.StatusUnprocessableEntity || e.Request != "POST /v1/licenses/renew" {
+		t.Errorf("reject entry = %+v", e)
+	}
+}
+
+func TestServeRecordsBadRequests(t *testing.T) {
+	ts := newTestServer(t)
+	if code := ts.post(t, "/v1/licenses/revoke", testToken, revokeRequest{Reason: "x"}, nil); code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", code)
+	}
+	entries := ts.wantActions(t, "reject")
+	if entries[0].Status != http.StatusBadRequest || entries[0].Error == "" {
+		t.Errorf("reject entry = %+v", entries[0])
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// usageColumns are the usage_metrics columns a counters CSV may carry; the
+// first three are required, the counters default to zero.
+var usageColumns = []string{
+	"tenant_id", "stream_id", "date",
+	"event_count", "api_request_count", "anomaly_count",
+	"ai_calls_count", "ai_input_tokens", "ai_output_tokens", "ai_cost_usd", "ai_charge_usd",
+}
+
+// runUsage handles `usage report|reconcile`: report runs on the self-hosted
+// installation, reconcile on the issuer's side.
+func runUsage(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: usage report --counters <csv> --license <key> --private-key-file <install key> --out <file>\n")
+		fmt.Fprintf(os.Stderr, "       usage reconcile --report <file> --keyring <file> [--json]\n")
+		return exitUsage
+	}
+	switch args[0] {
+	case "report":
+		return runUsageReport(args[1:])
+	case "reconcile":
+		return runUsageReconcile(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown usage command %q\n", args[0])
+		return exitUsage
+	}
+}
+
+// runUsageReport signs the installation's usage counters, as exported from
+// usage_metrics (e.g. with psql's \copy ... csv header), with the
+// installation key the license was activated with.
+func runUsageReport(args []string) int {
+	fs := flag.NewFlagSet("usage report", flag.ContinueOnError)
+	counters := fs.String("counters", "", "CSV export of usage_metrics, with a header row")
+	key := fs.String("license", os.Getenv("DRIFTLOCK_LICENSE_KEY"), "This installation's license (defaults to $DRIFTLOCK_LICENSE_KEY)")
+	out := fs.String("out", "", "File to write the signed report to")
+	from := fs.String("from", "", "First day to report, YYYY-MM-DD (default: earliest row)")
+	to := fs.String("to", "", "Last day to report, YYYY-MM-DD (default: latest row)")
+	var installKey signingKeyFlags
+	installKey.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *counters == "" || *key == "" || *out == "" {
+		fmt.Fprintf(os.Stderr, "Usage: usage report --counters <csv> --license <key> --private-key-file <install key> --out <file>\n")
+		return exitUsage
+	}
+
+	lic, err := license.Parse(*key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitMalformed
+	}
+	pub, err := license.InstallKey(lic)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	priv, err := installKey.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if license.KeyID(priv.Public()) != license.KeyID(pub) {
+		fmt.Fprintf(os.Stderr, "Error: this license was activated with installation key %s, not %s\n", license.KeyID(pub), license.KeyID(priv.Public()))
+		return 1
+	}
+
+	f, err := os.Open(*counters)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+	rows, err := readUsageCSV(f, *from, *to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *counters, err)
+		return 1
+	}
+
+	report := &license.UsageReport{License: lic.String(), PeriodStart: *from, PeriodEnd: *to, Rows: rows}
+	if report.PeriodStart == "" {
+		report.PeriodStart = rows[0].Date
+		for _, r := range rows {
+			report.PeriodStart = min(report.PeriodStart, r.Date)
+		}
+	}
+	if report.PeriodEnd == "" {
+		for _, r := range rows {
+			report.PeriodEnd = max(report.PeriodEnd, r.Date)
+		}
+	}
+	if err := license.SignUsageReport(priv, report, time.Now().Unix()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := license.WriteUsageReport(*out, report); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("REPORT=%s\n", *out)
+	fmt.Fprintf(os.Stderr, "\nSigned %d row(s) for %s to %s with installation key %s\n", len(rows), report.PeriodStart, report.PeriodEnd, report.KeyID)
+	return 0
+}
+
+// readUsageCSV parses a usage_metrics export, keeping rows dated from..to
+// (inclusive; empty means unbounded).
+func readUsageCSV(r io.Reader, from, to string) ([]license.UsageRow, error) {
+	for _, d := range []string{from, to} {
+		if _, err := time.Parse(time.DateOnly, d); d != "" && err != nil {
+			return nil, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", d)
+		}
+	}
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range usageColumns[:3] {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("header is missing %q column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := col[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	count := func(record []string, name string, line int, err *error) int64 {
+		v := field(record, name)
+		if v == "" || *err != nil {
+			return 0
+		}
+		n, perr := strconv.ParseInt(v, 10, 64)
+		if perr != nil {
+			*err = fmt.Errorf("line %d: %s: %q is not an integer", line, name, v)
+		}
+		return n
+	}
+	decimal := func(record []string, name string) string {
+		if v := field(record, name); v != "" {
+			return v
+		}
+		return "0"
+	}
+
+	var rows []license.UsageRow
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Postgres exports timestamps for DATE columns in some setups.
+		date, _, _ := strings.Cut(field(record, "date"), " ")
+		if (from != "" && date < from) || (to != "" && date > to) {
+			continue
+		}
+		var cerr error
+		row := license.UsageRow{
+			TenantID:        field(record, "tenant_id"),
+			StreamID:        field(record, "stream_id"),
+			Date:            date,
+			EventCount:      count(record, "event_count", line, &cerr),
+			APIRequestCount: count(record, "api_request_count", line, &cerr),
+			AnomalyCount:    count(record, "anomaly_count", line, &cerr),
+			AICallsCount:    count(record, "ai_calls_count", line, &cerr),
+			AIInputTokens:   count(record, "ai_input_tokens", line, &cerr),
+			AIOutputTokens:  count(record, "ai_output_tokens", line, &cerr),
+			AICostUSD:       decimal(record, "ai_cost_usd"),
+			AIChargeUSD:     decimal(record, "ai_charge_usd"),
+		}
+		if cerr != nil {
+			return nil, cerr
+		}
+		if err := row.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no usage rows in the reporting period")
+	}
+	return rows, nil
+}
+
+// runUsageReconcile verifies a usage report against the issuer keyring and
+// compares it with the licensed quota. It exits exitOverQuota if any month
+// is over quota or anything in the report does not match the license.
+func runUsageReconcile(args []string) int {
+	fs := flag.NewFlagSet("usage reconcile", flag.ContinueOnError)
+	reportPath := fs.String("report", "", "Signed usage report from the installation")
+	var publicKeys stringList
+	fs.Var(&publicKeys, "public-key", "Trusted issuer public key (repeatable)")
+	keyring := fs.String("keyring", "", "File of trusted issuer public keys")
+	asJSON := fs.Bool("json", false, "Print JSON instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *reportPath == "" {
+		fmt.Fprintf(os.Stderr, "Usage: usage reconcile --report <file> --keyring <file> [--json]\n")
+		return exitUsage
+	}
+	trusted, err := loadKeyring(publicKeys, *keyring)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if len(trusted) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --public-key or --keyring is required\n")
+		return exitUsage
+	}
+	report, err := license.ReadUsageReport(*reportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	lic, err := report.Verify(trusted)
+	switch {
+	case errors.Is(err, license.ErrMalformed):
+		fmt.Printf("malformed: %v\n", err)
+		return exitMalformed
+	case errors.Is(err, license.ErrUnknownKey):
+		fmt.Printf("unknown-key: %v\n", err)
+		return exitUnknownKey
+	case err != nil:
+		fmt.Printf("bad-signature: %v\n", err)
+		return exitBadSignature
+	}
+
+	rc := license.Reconcile(report, lic.Claims)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rc); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "MONTH\tEVENTS\tQUOTA\tSTREAMS\tLIMIT\tAPI REQUESTS\tANOMALIES\tAI CALLS\tAI CHARGE USD\tSTATUS\n")
+		for _, m := range rc.Months {
+			status := "ok"
+			if m.OverQuota {
+				status = "over"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\t%d\t%d\t%d\t%s\t%s\n", m.Month, m.Events, formatLimit(m.EventQuota),
+				m.Streams, formatLimit(m.StreamLimit), m.APIRequests, m.Anomalies, m.AICalls, m.AIChargeUSD, status)
+		}
+		w.Flush()
+		for _, f := range rc.Findings {
+			fmt.Printf("finding: %s\n", f)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Report from installation %s for tenant %s, %s to %s\n", report.KeyID, rc.TenantID, report.PeriodStart, report.PeriodEnd)
+	if rc.OverQuota() {
+		return exitOverQuota
+	}
+	return 0
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Shannon-Labs/driftlock/scripts/license"
+)
+
+// vectorsNow is the fixed verification time of every conformance vector,
+// 2026-01-01T00:00:00Z.
+const vectorsNow = 1767225600
+
+// vectorFile is the conformance corpus shared with the Rust verifier. The
+// keys are derived from fixed strings and P-256 signatures are RFC 6979
+// deterministic, so regenerating it yields the same bytes.
+type vectorFile struct {
+	Version int         `json:"version"`
+	Comment string      `json:"comment"`
+	Now     int64       `json:"now"`
+	Keys    []vectorKey `json:"keys"`
+	Vectors []vector    `json:"vectors"`
+}
+
+type vectorKey struct {
+	Name       string `json:"name"`
+	KeyID      string `json:"key_id"`
+	Alg        string `json:"alg"`
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+// vector is one license and the verdict a verifier trusting the named keys
+// must reach at vectorFile.Now. Verdicts use the words verify prints.
+type vector struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	License     string   `json:"license"`
+	Trusted     []string `json:"trusted"`
+	Machine     string   `json:"machine,omitempty"`
+	Verdict     string   `json:"verdict"`
+}
+
+// runVectors writes the conformance corpus, or with --check verifies this
+// package against an existing one.
+func runVectors(args []string) int {
+	fs := flag.NewFlagSet("vectors", flag.ContinueOnError)
+	out := fs.String("out", "", "File to write the corpus to (default: stdout)")
+	check := fs.String("check", "", "Corpus to check the Go verifier against instead of generating")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *check != "" {
+		return checkVectors(*check)
+	}
+
+	vf, err := buildVectors()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	b, err := json.MarshalIndent(vf, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	b = append(b, '\n')
+	if *out == "" {
+		os.Stdout.Write(b)
+		return 0
+	}
+	if err := os.WriteFile(*out, b, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d vectors to %s\n", len(vf.Vectors), *out)
+	return 0
+}
+
+// vectorKeyHex derives a fixed private key from name.
+func vectorKeyHex(alg, name string) string {
+	sum := sha256.Sum256([]byte("driftlock conformance key: " + name))
+	if alg == license.AlgEd25519 {
+		return license.AlgEd25519 + ":" + hex.EncodeToString(sum[:])
+	}
+	return hex.EncodeToString(sum[:])
+}
+
+func buildVectors() (*vectorFile, error) {
+	vf := &vectorFile{
+		Version: 1,
+		Comment: "Generated by `generate-license-key vectors`; do not edit. Verify each license at `now` " +
+			"with only the `trusted` keys, no clock skew, grace period or per-tier signer policy.",
+		Now: vectorsNow,
+	}
+	signers := map[string]crypto.Signer{}
+	for _, k := range []struct{ name, alg string }{
+		{"p256-a", license.AlgP256},
+		{"p256-b", license.AlgP256},
+		{"ed25519-a", license.AlgEd25519},
+	} {
+		priv, err := license.ParsePrivateKeyHex(vectorKeyHex(k.alg, k.name))
+		if err != nil {
+			return nil, fmt.Errorf("derive %s: %w", k.name, err)
+		}
+		signers[k.name] = priv
+		vf.Keys = append(vf.Keys, vectorKey{
+			Name:       k.name,
+			KeyID:      license.KeyID(priv.Public()),
+			Alg:        k.alg,
+			PublicKey:  license.PublicKeyHex(priv.Public()),
+			PrivateKey: license.PrivateKeyHex(priv),
+		})
+	}
+	a, b, ed := signers["p256-a"], signers["p256-b"], signers["ed25519-a"]
+	idA, idB, idEd := license.KeyID(a.Public()), license.KeyID(b.Public()), license.KeyID(ed.Public())
+
+	day := int64(24 * 60 * 60)
+	inAYear := vectorsNow + 365*day
+	claims := func(tier string) license.Claims {
+		return license.Claims{
+			TenantID:       "tenant-conformance",
+			Tier:           tier,
+			Plan:           "radar",
+			EventsPerMonth: 1000000,
+			StreamLimit:    10,
+			Features:       []string{"ai_explanations", "openzl"},
+			NotBefore:      vectorsNow - day,
+			IssuedAt:       vectorsNow - day,
+			Expiry:         inAYear,
+		}
+	}
+	var err error
+	sign := func(priv crypto.Signer, lic *license.License) string {
+		if err != nil {
+			return ""
+		}
+		var key string
+		key, err = license.Sign(priv, lic)
+		return key
+	}
+	v2 := func(priv crypto.Signer, c license.Claims) *license.License {
+		if err != nil {
+			return nil
+		}
+		var lic *license.License
+		lic, err = license.NewV2(c, license.KeyID(priv.Public()))
+		return lic
+	}
+	add := func(name, desc, key, verdict string, trusted ...string) {
+		vf.Vectors = append(vf.Vectors, vector{Name: name, Description: desc, License: key, Trusted: trusted, Verdict: verdict})
+	}
+
+	validV1 := sign(a, license.NewV1("PRO", inAYear, idA))
+	add("v1-valid", "TIER.EXPIRY.KEYID.SIG signed by a trusted P-256 key", validV1, "valid", idA)
+	add("v1-valid-ed25519", "v1 signed by a trusted Ed25519 key; the key segment carries the ed25519: marker",
+		sign(ed, license.NewV1("PRO", inAYear, idEd)), "valid", idEd)
+	add("v1-valid-keyring", "v1 checked against a keyring holding several keys", validV1, "valid", idB, idA, idEd)
+	legacy := sign(a, license.NewV1("EVAL", inAYear, ""))
+	add("legacy-valid", "Legacy TIER.EXPIRY.SIG without a key ID, tried against every trusted P-256 key", legacy, "valid", idB, idA)
+	add("legacy-untrusted", "Legacy license whose signer is not trusted", legacy, "bad-signature", idB)
+	add("legacy-tampered-tier", "Legacy license with the tier edited after signing",
+		strings.Replace(legacy, "EVAL.", "ENTERPRISE.", 1), "bad-signature", idA)
+	add("legacy-ed25519-only", "Legacy licenses are never checked against Ed25519 keys", legacy, "bad-signature", idEd)
+
+	add("v1-expired", "Expired one second before now",
+		sign(a, license.NewV1("PRO", vectorsNow-1, idA)), "expired", idA)
+	add("v1-expiry-boundary", "Expiry equal to now counts as expired",
+		sign(a, license.NewV1("PRO", vectorsNow, idA)), "expired", idA)
+	add("v1-expires-next-second", "Expiry one second after now is still valid",
+		sign(a, license.NewV1("PRO", vectorsNow+1, idA)), "valid", idA)
+
+	add("wrong-key-unknown", "Signed by a key the verifier does not trust", validV1, "unknown-key", idB)
+	add("wrong-key-impersonated", "Names trusted key A but was signed by key B",
+		sign(b, license.NewV1("PRO", inAYear, idA)), "bad-signature", idA)
+	add("wrong-key-alg-confusion", "Claims to be Ed25519 under a P-256 key ID",
+		strings.Replace(validV1, "."+idA+".", ".ed25519:"+idA+".", 1), "bad-signature", idA)
+
+	parts := strings.Split(validV1, ".")
+	sigB64 := parts[len(parts)-1]
+	prefix := strings.TrimSuffix(validV1, sigB64)
+	add("signature-truncated", "Last four base64 characters of the signature removed",
+		prefix+sigB64[:len(sigB64)-4], "malformed", idA)
+	add("signature-missing", "Empty signature segment", prefix, "malformed", idA)
+	add("signature-std-padding", "Signature written with = padding; SIG is unpadded base64",
+		prefix+sigB64+"=", "malformed", idA)
+	raw, _ := base64.RawStdEncoding.DecodeString(sigB64)
+	raw[10] ^= 0x01
+	add("signature-bit-flip", "One bit of r flipped",
+		prefix+base64.RawStdEncoding.EncodeToString(raw), "bad-signature", idA)
+	highS, _ := base64.RawStdEncoding.DecodeString(sigB64)
+	sigS := new(big.Int).SetBytes(highS[32:])
+	new(big.Int).Sub(elliptic.P256().Params().N, sigS).FillBytes(highS[32:])
+	add("signature-high-s", "s replaced by n-s: still a valid P-256 signature, so revocation must not key on the signature bytes",
+		prefix+base64.RawStdEncoding.EncodeToString(highS), "valid", idA)
+
+	// Find a license whose r or s has a leading zero byte: the padded form
+	// must verify, the unpadded 63-byte form must be rejected.
+	for i := int64(0); err == nil; i++ {
+		key := sign(a, license.NewV1("PRO", inAYear+i, idA))
+		if err != nil {
+			break
+		}
+		parts := strings.Split(key, ".")
+		sig, _ := base64.RawStdEncoding.DecodeString(parts[3])
+		var short []byte
+		switch {
+		case sig[0] == 0:
+			short = append(append([]byte{}, sig[1:32]...), sig[32:]...)
+		case sig[32] == 0:
+			short = append(append([]byte{}, sig[:32]...), sig[33:]...)
+		default:
+			continue
+		}
+		add("scalar-zero-padded", "r or s has a leading zero byte and is left-padded to 32 bytes", key, "valid", idA)
+		add("scalar-not-padded", "The same signature with the leading zero byte dropped (63 bytes)",
+			strings.Join(append(parts[:3], base64.RawStdEncoding.EncodeToString(short)), "."), "malformed", idA)
+		break
+	}
+
+	add("whitespace-surrounding", "Leading spaces and a trailing newline are ignored", "  "+validV1+"\n", "valid", idA)
+	add("whitespace-crlf", "Trailing CRLF from a Windows .env file is ignored", validV1+"\r\n", "valid", idA)
+	add("whitespace-env-prefix", "Pasted DRIFTLOCK_LICENSE_KEY= assignment is accepted",
+		"DRIFTLOCK_LICENSE_KEY="+validV1, "valid", idA)
+	add("whitespace-export-prefix", "Pasted export DRIFTLOCK_LICENSE_KEY= line is accepted",
+		"export DRIFTLOCK_LICENSE_KEY="+validV1, "valid", idA)
+	add("whitespace-inside", "A space inside the signature is not tolerated",
+		prefix+sigB64[:20]+" "+sigB64[20:], "malformed", idA)
+
+	add("malformed-parts", "Only two dot-separated parts", "PRO."+fmt.Sprint(inAYear), "malformed", idA)
+	add("malformed-expiry", "Expiry is not an integer",
+		strings.Replace(validV1, "."+fmt.Sprint(inAYear)+".", ".soon.", 1), "malformed", idA)
+
+	validV2 := v2(a, claims("PRO"))
+	v2Key := sign(a, validV2)
+	add("v2-valid", "v2.CLAIMS.KEYID.SIG with canonical claims", v2Key, "valid", idA)
+	add("v2-valid-ed25519", "v2 signed by Ed25519", sign(ed, v2(ed, claims("PRO"))), "valid", idEd)
+	future := claims("PRO")
+	future.NotBefore = vectorsNow + 60
+	add("v2-not-yet-valid", "Not-before one minute after now", sign(a, v2(a, future)), "not-yet-valid", idA)
+	expired := claims("PRO")
+	expired.NotBefore, expired.IssuedAt, expired.Expiry = vectorsNow-30*day, vectorsNow-30*day, vectorsNow-day
+	add("v2-expired", "v2 expired a day before now", sign(a, v2(a, expired)), "expired", idA)
+	if err == nil {
+		upgraded := v2(a, claims("ENTERPRISE"))
+		upgraded.Alg, upgraded.Signature = validV2.Alg, validV2.Signature
+		add("v2-tampered-claims", "Tier in the claims changed after signing, signature kept", upgraded.String(), "bad-signature", idA)
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"v": 2, "tenant_id": "tenant-conformance", "tier": "PRO", "plan": "radar", "events_per_month": 0,
+		"stream_limit": 0, "features": []string{"openzl", "ai_explanations"}, "nbf": vectorsNow - day,
+		"iat": vectorsNow - day, "exp": inAYear,
+	})
+	v2Parts := strings.Split(v2Key, ".")
+	add("v2-noncanonical-features", "Features not in sorted order are rejected before the signature is checked",
+		"v2."+base64.RawURLEncoding.EncodeToString(payload)+"."+v2Parts[2]+"."+v2Parts[3], "malformed", idA)
+	add("v2-unknown-claim", "Claims with an unknown field are rejected",
+		"v2."+base64.RawURLEncoding.EncodeToString([]byte(`{"v":2,"extra":true}`))+"."+v2Parts[2]+"."+v2Parts[3], "malformed", idA)
+
+	threshold := claims("PRO")
+	threshold.Signers = 2
+	multi := v2(a, threshold)
+	sign(a, multi)
+	if err == nil {
+		_, err = license.Cosign(ed, multi)
+	}
+	if err == nil {
+		add("multi-valid", "2-of-N license signed by two trusted keys", multi.String(), "valid", idA, idEd)
+		add("multi-one-trusted", "2-of-N license where only one signer is trusted", multi.String(), "needs-signers", idA)
+		single := *multi
+		single.Cosignatures = nil
+		add("multi-stripped", "Cosignature removed from a 2-of-N license", single.String(), "needs-signers", idA, idEd)
+	}
+
+	bound := claims("PRO")
+	bound.Machine = license.HashMachineID("conformance-machine")
+	bound.ActivationID = strings.Repeat("ab", 16)
+	boundKey := sign(a, v2(a, bound))
+	vf.Vectors = append(vf.Vectors,
+		vector{Name: "bound-right-machine", Description: "Host-bound license on its own machine",
+			License: boundKey, Trusted: []string{idA}, Machine: bound.Machine, Verdict: "valid"},
+		vector{Name: "bound-wrong-machine", Description: "Host-bound license on another machine",
+			License: boundKey, Trusted: []string{idA}, Machine: license.HashMachineID("other"), Verdict: "wrong-machine"})
+
+	if err != nil {
+		return nil, err
+	}
+	return vf, nil
+}
+
+// checkVectors runs every vector in path through license.Verifier and
+// reports the ones whose verdict differs.
+func checkVectors(path string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var vf vectorFile
+	if err := json.Unmarshal(b, &vf); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+		return 1
+	}
+	pubs := map[string]crypto.PublicKey{}
+	for _, k := range vf.Keys {
+		pub, err := license.ParsePublicKeyHex(k.PublicKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: key %s: %v\n", k.Name, err)
+			return 1
+		}
+		pubs[k.KeyID] = pub
+	}
+
+	failed := 0
+	for _, v := range vf.Vectors {
+		keys := license.Keyring{}
+		for _, id := range v.Trusted {
+			keys[id] = pubs[id]
+		}
+		// An empty MinSigners turns off DefaultMinSigners, as the corpus asks.
+		verifier := &license.Verifier{Keys: keys, Machine: v.Machine, MinSigners: map[string]int{}}
+		_, err := verifier.Verify(v.License, time.Unix(vf.Now, 0))
+		if got := verdict(err); got != v.Verdict {
+			fmt.Printf("FAIL %s: want %s, got %s (%v)\n", v.Name, v.Verdict, got, err)
+			failed++
+		}
+	}
+	fmt.Printf("%d/%d vectors passed\n", len(vf.Vectors)-failed, len(vf.Vectors))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
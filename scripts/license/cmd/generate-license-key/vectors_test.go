@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+const conformancePath = "../../testdata/conformance.json"
+
+func TestConformanceVectors(t *testing.T) {
+	if code := checkVectors(conformancePath); code != 0 {
+		t.Fatalf("checkVectors(%s) = %d, want 0", conformancePath, code)
+	}
+}
+
+// TestConformanceUpToDate fails when the corpus on disk no longer matches
+// what `vectors` generates; regenerate it with
+// `go run ./cmd/generate-license-key vectors --out testdata/conformance.json`.
+func TestConformanceUpToDate(t *testing.T) {
+	vf, err := buildVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.MarshalIndent(vf, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(conformancePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, append(want, '\n')) {
+		t.Fatalf("%s is stale; regenerate it", conformancePath)
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/Shannon-Labs/driftlock/scripts/license
+
+go 1.24.0
+
+require golang.org/x/crypto v0.36.0
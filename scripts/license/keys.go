@@ -0,0 +1,194 @@
+package license
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// LegacyPublicKeyHex is the original license.go public key. Licenses without
+// a key ID (TIER.EXPIRY.SIG) were all signed by its private key. It is the
+// P-256 base point, so its private key is 1 and anyone can sign with it:
+// never trust it by default.
+const LegacyPublicKeyHex = "046b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c2964fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5"
+
+// Signing algorithms. P-256 keys are written as bare hex for compatibility;
+// Ed25519 keys carry an "ed25519:" prefix in key files and license strings.
+const (
+	AlgP256    = "p256"
+	AlgEd25519 = "ed25519"
+)
+
+// Keyring holds the public keys a verifier trusts, indexed by key ID. Values
+// are *ecdsa.PublicKey or ed25519.PublicKey.
+type Keyring map[string]crypto.PublicKey
+
+// NewKeyring returns a keyring trusting pubs.
+func NewKeyring(pubs ...crypto.PublicKey) Keyring {
+	k := make(Keyring, len(pubs))
+	for _, pub := range pubs {
+		k.Add(pub)
+	}
+	return k
+}
+
+// Add trusts pub and returns its key ID.
+func (k Keyring) Add(pub crypto.PublicKey) string {
+	id := KeyID(pub)
+	k[id] = pub
+	return id
+}
+
+// ReadKeyring parses one hex public key per line; blank lines and lines
+// starting with # are ignored.
+func ReadKeyring(r io.Reader) (Keyring, error) {
+	k := Keyring{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pub, err := ParsePublicKeyHex(line)
+		if err != nil {
+			return nil, err
+		}
+		k.Add(pub)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read keyring: %w", err)
+	}
+	return k, nil
+}
+
+// GenerateKey creates a signing key for alg.
+func GenerateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case AlgP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q (expected %s or %s)", alg, AlgP256, AlgEd25519)
+	}
+}
+
+// Alg reports the signing algorithm of a public key, or "" if unsupported.
+func Alg(pub crypto.PublicKey) string {
+	switch pub.(type) {
+	case *ecdsa.PublicKey:
+		return AlgP256
+	case ed25519.PublicKey:
+		return AlgEd25519
+	default:
+		return ""
+	}
+}
+
+// KeyID derives a short, stable identifier for a public key: the first
+// 8 bytes of SHA-256 over its raw encoding, hex encoded.
+func KeyID(pub crypto.PublicKey) string {
+	var raw []byte
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		raw, _ = hex.DecodeString(PublicKeyHex(pub))
+	case ed25519.PublicKey:
+		raw = pub
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParsePrivateKeyHex decodes a P-256 private scalar (D) from hex, or an
+// Ed25519 seed written as "ed25519:<hex>".
+func ParsePrivateKeyHex(privHex string) (crypto.Signer, error) {
+	privHex = strings.TrimSpace(privHex)
+	if seedHex, ok := strings.CutPrefix(privHex, AlgEd25519+":"); ok {
+		seed, err := hex.DecodeString(seedHex)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid ed25519 private key hex")
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+
+	d, ok := new(big.Int).SetString(privHex, 16)
+	if !ok || d.Sign() <= 0 || d.Cmp(elliptic.P256().Params().N) >= 0 {
+		return nil, fmt.Errorf("invalid private key hex")
+	}
+
+	curve := elliptic.P256()
+	priv := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve},
+		D:         d,
+	}
+	priv.PublicKey.X, priv.PublicKey.Y = curve.ScalarBaseMult(padScalar(d.Bytes()))
+	return priv, nil
+}
+
+// PrivateKeyHex encodes a private key in the format ParsePrivateKeyHex reads.
+func PrivateKeyHex(priv crypto.Signer) string {
+	switch priv := priv.(type) {
+	case *ecdsa.PrivateKey:
+		return hex.EncodeToString(padScalar(priv.D.Bytes()))
+	case ed25519.PrivateKey:
+		return AlgEd25519 + ":" + hex.EncodeToString(priv.Seed())
+	default:
+		return ""
+	}
+}
+
+// ParsePublicKeyHex decodes an uncompressed P-256 public key (04 || X || Y)
+// or an Ed25519 public key written as "ed25519:<hex>".
+func ParsePublicKeyHex(pubHex string) (crypto.PublicKey, error) {
+	pubHex = strings.TrimSpace(pubHex)
+	if rawHex, ok := strings.CutPrefix(pubHex, AlgEd25519+":"); ok {
+		raw, err := hex.DecodeString(rawHex)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key format")
+		}
+		return ed25519.PublicKey(raw), nil
+	}
+
+	pubKeyBytes, err := hex.DecodeString(pubHex)
+	if err != nil || len(pubKeyBytes) != 65 || pubKeyBytes[0] != 4 {
+		return nil, fmt.Errorf("invalid public key format")
+	}
+	curve := elliptic.P256()
+	x := new(big.Int).SetBytes(pubKeyBytes[1:33])
+	y := new(big.Int).SetBytes(pubKeyBytes[33:])
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("public key is not on the P-256 curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// PublicKeyHex encodes pub in the format ParsePublicKeyHex reads.
+func PublicKeyHex(pub crypto.PublicKey) string {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("04%x%x", padScalar(pub.X.Bytes()), padScalar(pub.Y.Bytes()))
+	case ed25519.PublicKey:
+		return AlgEd25519 + ":" + hex.EncodeToString(pub)
+	default:
+		return ""
+	}
+}
+
+func padScalar(b []byte) []byte {
+	if len(b) == 32 {
+		return b
+	}
+	buf := make([]byte, 32)
+	copy(buf[32-len(b):], b)
+	return buf
+}
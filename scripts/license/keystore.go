@@ -0,0 +1,185 @@
+package license
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrWrongPassphrase is returned when a keystore cannot be decrypted.
+var ErrWrongPassphrase = errors.New("license: wrong keystore passphrase")
+
+// Default scrypt cost parameters (N=2^15, r=8, p=1), as recommended for
+// interactive logins; unlocking takes roughly 100ms.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	keystoreSalt = 16
+)
+
+// Keystore is a passphrase-encrypted signing key. The P-256 scalar or Ed25519
+// seed is sealed with AES-256-GCM under a scrypt-derived key; the key ID and
+// public key are bound in as additional data so they cannot be swapped.
+type Keystore struct {
+	Version    int          `json:"version"`
+	Alg        string       `json:"alg"`
+	KeyID      string       `json:"key_id"`
+	PublicKey  string       `json:"public_key"`
+	KDF        string       `json:"kdf"`
+	KDFParams  scryptParams `json:"kdf_params"`
+	Cipher     string       `json:"cipher"`
+	Nonce      string       `json:"nonce"`
+	Ciphertext string       `json:"ciphertext"`
+}
+
+type scryptParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+// EncryptKeystore seals priv under passphrase.
+func EncryptKeystore(priv crypto.Signer, passphrase []byte) (*Keystore, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("keystore: empty passphrase")
+	}
+	salt := make([]byte, keystoreSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	var secret []byte
+	switch priv := priv.(type) {
+	case *ecdsa.PrivateKey:
+		secret = padScalar(priv.D.Bytes())
+	case ed25519.PrivateKey:
+		secret = priv.Seed()
+	default:
+		return nil, fmt.Errorf("keystore: unsupported key type %T", priv)
+	}
+	ks := &Keystore{
+		Version:   1,
+		Alg:       Alg(priv.Public()),
+		KeyID:     KeyID(priv.Public()),
+		PublicKey: PublicKeyHex(priv.Public()),
+		KDF:       "scrypt",
+		KDFParams: scryptParams{N: scryptN, R: scryptR, P: scryptP, Salt: base64.StdEncoding.EncodeToString(salt)},
+		Cipher:    "aes-256-gcm",
+	}
+	gcm, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nil, nonce, secret, ks.additionalData())
+	ks.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	ks.Ciphertext = base64.StdEncoding.EncodeToString(sealed)
+	return ks, nil
+}
+
+// Unlock decrypts the signing key and checks it matches the recorded public key.
+func (ks *Keystore) Unlock(passphrase []byte) (crypto.Signer, error) {
+	if ks.Version != 1 || ks.KDF != "scrypt" || ks.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("keystore: unsupported format (version %d, %s, %s)", ks.Version, ks.KDF, ks.Cipher)
+	}
+	if ks.Alg != AlgP256 && ks.Alg != AlgEd25519 {
+		return nil, fmt.Errorf("keystore: unknown algorithm %q", ks.Alg)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(ks.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: invalid nonce: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ks.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: invalid ciphertext: %w", err)
+	}
+	gcm, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("keystore: invalid nonce length %d", len(nonce))
+	}
+	d, err := gcm.Open(nil, nonce, sealed, ks.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	privHex := fmt.Sprintf("%x", d)
+	if ks.Alg == AlgEd25519 {
+		privHex = AlgEd25519 + ":" + privHex
+	}
+	priv, err := ParsePrivateKeyHex(privHex)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: %w", err)
+	}
+	if PublicKeyHex(priv.Public()) != ks.PublicKey {
+		return nil, fmt.Errorf("keystore: decrypted key does not match recorded public key")
+	}
+	return priv, nil
+}
+
+func (ks *Keystore) aead(passphrase []byte) (cipher.AEAD, error) {
+	salt, err := base64.StdEncoding.DecodeString(ks.KDFParams.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: invalid salt: %w", err)
+	}
+	p := ks.KDFParams
+	key, err := scrypt.Key(passphrase, salt, p.N, p.R, p.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (ks *Keystore) additionalData() []byte {
+	return []byte(ks.KeyID + "." + ks.PublicKey)
+}
+
+// ReadKeystore loads a keystore file.
+func ReadKeystore(path string) (*Keystore, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ks Keystore
+	if err := json.Unmarshal(b, &ks); err != nil {
+		return nil, fmt.Errorf("keystore %s: %w", path, err)
+	}
+	return &ks, nil
+}
+
+// WriteKeystore saves ks to path with owner-only permissions, refusing to
+// overwrite an existing file.
+func WriteKeystore(path string, ks *Keystore) error {
+	b, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
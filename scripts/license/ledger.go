@@ -0,0 +1,153 @@
+package license
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrLedgerTampered is returned when the issuance ledger's hash chain breaks.
+var ErrLedgerTampered = errors.New("license: ledger tampered")
+
+// genesisHash is the Prev value of the first ledger entry.
+var genesisHash = strings.Repeat("0", 64)
+
+// LedgerEntry is one line of the append-only issuance ledger. Hash covers the
+// entry's canonical JSON with Hash empty, and Prev links it to the line above,
+// so editing, reordering or deleting any line breaks the chain. Authenticated
+// requests the serve command refused are recorded as action "reject" with
+// Request, Status and Error set.
+type LedgerEntry struct {
+	Seq         int64   `json:"seq"`
+	Action      string  `json:"action"`
+	Time        string  `json:"time"`
+	Issuer      string  `json:"issuer"`
+	KeyID       string  `json:"key_id"`
+	Fingerprint string  `json:"fingerprint"`
+	Customer    string  `json:"customer,omitempty"`
+	Tier        string  `json:"tier"`
+	Expiry      int64   `json:"expiry"`
+	Claims      *Claims `json:"claims,omitempty"`
+	Request     string  `json:"request,omitempty"`
+	Status      int     `json:"status,omitempty"`
+	Error       string  `json:"error,omitempty"`
+	Prev        string  `json:"prev"`
+	Hash        string  `json:"hash"`
+}
+
+func (e LedgerEntry) computeHash() (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Ledger appends entries to a hash-chained JSONL file.
+type Ledger struct {
+	path string
+	seq  int64
+	last string
+}
+
+// OpenLedger verifies the existing ledger at path, if any, and positions it
+// for appending. A missing file starts a new chain.
+func OpenLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path, last: genesisHash}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	last, err := VerifyLedger(f)
+	if err != nil {
+		return nil, fmt.Errorf("ledger %s: %w", path, err)
+	}
+	if last != nil {
+		l.seq, l.last = last.Seq, last.Hash
+	}
+	return l, nil
+}
+
+// Append chains e onto the ledger, fills in Seq, Prev and Hash, and syncs the
+// line to disk before returning it.
+func (l *Ledger) Append(e LedgerEntry) (LedgerEntry, error) {
+	e.Seq = l.seq + 1
+	e.Prev = l.last
+	hash, err := e.computeHash()
+	if err != nil {
+		return e, err
+	}
+	e.Hash = hash
+	b, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return e, err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return e, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return e, err
+	}
+	if err := f.Close(); err != nil {
+		return e, err
+	}
+	l.seq, l.last = e.Seq, e.Hash
+	return e, nil
+}
+
+// VerifyLedger walks the chain and returns the last entry (nil for an empty
+// ledger). Any broken link is reported as ErrLedgerTampered with its line.
+func VerifyLedger(r io.Reader) (*LedgerEntry, error) {
+	var last *LedgerEntry
+	prev := genesisHash
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	line := 0
+	for sc.Scan() {
+		line++
+		var e LedgerEntry
+		dec := json.NewDecoder(strings.NewReader(sc.Text()))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e); err != nil {
+			return last, fmt.Errorf("%w: line %d: %v", ErrLedgerTampered, line, err)
+		}
+		if e.Seq != int64(line) {
+			return last, fmt.Errorf("%w: line %d: sequence %d out of order", ErrLedgerTampered, line, e.Seq)
+		}
+		if e.Prev != prev {
+			return last, fmt.Errorf("%w: line %d: previous hash does not match line %d", ErrLedgerTampered, line, line-1)
+		}
+		want, err := e.computeHash()
+		if err != nil {
+			return last, err
+		}
+		if e.Hash != want {
+			return last, fmt.Errorf("%w: line %d: entry hash mismatch", ErrLedgerTampered, line)
+		}
+		prev = e.Hash
+		last = &e
+	}
+	if err := sc.Err(); err != nil {
+		return last, err
+	}
+	return last, nil
+}
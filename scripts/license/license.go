@@ -0,0 +1,505 @@
+// Package license signs, parses and verifies DRIFTLOCK_LICENSE_KEY values.
+//
+// Three encodings are understood:
+//
+//	TIER.EXPIRY.SIG           legacy v1, signed by the original license.go key
+//	TIER.EXPIRY.KEYID.SIG     v1 with a key ID, so signing keys can rotate
+//	v2.CLAIMS.KEYID.SIG       v2, CLAIMS is base64url canonical JSON
+//
+// KEYID is written as "ed25519:KEYID" for Ed25519 keys. SIG covers everything
+// before the final dot: for P-256 it is r || s (each padded to 32 bytes) over
+// its SHA-256, for Ed25519 the 64-byte signature over the bytes themselves.
+//
+// A v2 license signed by several keys lists them as KEYID,KEYID,... with the
+// signatures in the same order; each signature covers v2.CLAIMS.KEYID for its
+// own key, and the claims state how many signers are required.
+package license
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// v2Prefix marks structured-claims licenses.
+const v2Prefix = "v2"
+
+var (
+	// ErrMalformed is returned when a license string cannot be decoded.
+	ErrMalformed = errors.New("license: malformed")
+	// ErrBadSignature is returned when no trusted key verifies the signature.
+	ErrBadSignature = errors.New("license: bad signature")
+	// ErrUnknownKey is returned when the license names a key ID that is not
+	// in the verifier's keyring.
+	ErrUnknownKey = errors.New("license: unknown key")
+	// ErrExpired is returned for correctly signed licenses past their expiry.
+	ErrExpired = errors.New("license: expired")
+	// ErrNotYetValid is returned for v2 licenses before their not-before time.
+	ErrNotYetValid = errors.New("license: not yet valid")
+	// ErrInsufficientSignatures is returned when fewer trusted keys signed a
+	// license than its claims or the verifier's policy require.
+	ErrInsufficientSignatures = errors.New("license: insufficient signatures")
+)
+
+// License is a parsed license key. KeyID is empty for legacy licenses;
+// Claims is set only for v2 licenses, whose Tier and Expiry mirror the claims.
+// Alg is the signing algorithm (AlgP256 or AlgEd25519) and is set by Sign.
+// Cosignatures holds the signatures added after the first by Cosign.
+type License struct {
+	Version      int
+	Tier         string
+	Expiry       int64 // Unix seconds
+	KeyID        string
+	Alg          string
+	Claims       *Claims
+	Signature    []byte
+	Cosignatures []Cosignature
+
+	payload string
+}
+
+// Cosignature is one additional signer's signature on a v2 license.
+type Cosignature struct {
+	KeyID     string
+	Alg       string
+	Signature []byte
+}
+
+// NewV1 returns an unsigned v1 license. Pass an empty keyID for the legacy
+// format.
+func NewV1(tier string, expiry int64, keyID string) *License {
+	return &License{Version: 1, Tier: tier, Expiry: expiry, KeyID: keyID}
+}
+
+// NewV2 returns an unsigned v2 license carrying claims.
+func NewV2(claims Claims, keyID string) (*License, error) {
+	claims.Version = 2
+	if err := claims.Validate(); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("marshal claims: %w", err)
+	}
+	return &License{
+		Version: 2,
+		Tier:    claims.Tier,
+		Expiry:  claims.Expiry,
+		KeyID:   keyID,
+		Claims:  &claims,
+		payload: base64.RawURLEncoding.EncodeToString(raw),
+	}, nil
+}
+
+// keySegment is the KEYID part of the wire format, which also marks the
+// algorithm for non-P-256 keys.
+func (l *License) keySegment() string {
+	return keySegment(l.KeyID, l.Alg)
+}
+
+func keySegment(keyID, alg string) string {
+	if alg == "" || alg == AlgP256 {
+		return keyID
+	}
+	return alg + ":" + keyID
+}
+
+// SignedMessage returns the bytes covered by the (first) license signature.
+func (l *License) SignedMessage() []byte {
+	switch {
+	case l.Version == 2:
+		return l.cosignedMessage(l.KeyID, l.Alg)
+	case l.KeyID == "":
+		return []byte(fmt.Sprintf("%s.%d", l.Tier, l.Expiry))
+	default:
+		return []byte(fmt.Sprintf("%s.%d.%s", l.Tier, l.Expiry, l.keySegment()))
+	}
+}
+
+// signedContent is what every signer of the license vouches for: the v1
+// signed message, or "v2.CLAIMS" without any key segment.
+func (l *License) signedContent() []byte {
+	if l.Version == 2 {
+		return []byte(v2Prefix + "." + l.payload)
+	}
+	return l.SignedMessage()
+}
+
+// cosignedMessage returns the bytes a v2 signer with keyID signs.
+func (l *License) cosignedMessage(keyID, alg string) []byte {
+	return []byte(v2Prefix + "." + l.payload + "." + keySegment(keyID, alg))
+}
+
+// String encodes the license in its wire format.
+func (l *License) String() string {
+	if len(l.Cosignatures) == 0 {
+		return string(l.SignedMessage()) + "." + base64.RawStdEncoding.EncodeToString(l.Signature)
+	}
+	segs := []string{l.keySegment()}
+	sigs := []string{base64.RawStdEncoding.EncodeToString(l.Signature)}
+	for _, c := range l.Cosignatures {
+		segs = append(segs, keySegment(c.KeyID, c.Alg))
+		sigs = append(sigs, base64.RawStdEncoding.EncodeToString(c.Signature))
+	}
+	return v2Prefix + "." + l.payload + "." + strings.Join(segs, ",") + "." + strings.Join(sigs, ",")
+}
+
+// RequiredSigners is the number of distinct keys the license's own claims
+// say must sign it.
+func (l *License) RequiredSigners() int {
+	if l.Claims != nil && l.Claims.Signers > 1 {
+		return l.Claims.Signers
+	}
+	return 1
+}
+
+// signatures returns every signature on the license, first signer first.
+func (l *License) signatures() []Cosignature {
+	sigs := []Cosignature{{KeyID: l.KeyID, Alg: l.Alg, Signature: l.Signature}}
+	return append(sigs, l.Cosignatures...)
+}
+
+// CheckSignerPolicy reports whether lic could ever satisfy DefaultMinSigners:
+// a tier that needs several signers must be a v2 license whose claims require
+// at least that many. Issuers check it before signing, so no single key
+// holder can hand out such a license.
+func CheckSignerPolicy(lic *License) error {
+	need := DefaultMinSigners[strings.ToUpper(lic.Tier)]
+	if need > 1 && (lic.Version != 2 || lic.RequiredSigners() < need) {
+		return fmt.Errorf("%w: %s licenses must be v2 with at least %d required signers", ErrInsufficientSignatures, lic.Tier, need)
+	}
+	return nil
+}
+
+// Sign signs lic in place with priv and returns the encoded license. The
+// license's Alg is taken from the key. Licenses that fail CheckSignerPolicy
+// are refused.
+func Sign(priv crypto.Signer, lic *License) (string, error) {
+	if lic.Version == 2 && lic.KeyID == "" {
+		return "", fmt.Errorf("license: v2 licenses require a key ID")
+	}
+	if err := CheckSignerPolicy(lic); err != nil {
+		return "", err
+	}
+	lic.Alg = Alg(priv.Public())
+	if lic.KeyID == "" && lic.Alg != AlgP256 {
+		return "", fmt.Errorf("license: legacy licenses must be signed with P-256")
+	}
+	sig, err := signMessage(priv, lic.SignedMessage())
+	if err != nil {
+		return "", err
+	}
+	lic.Signature = sig
+	return lic.String(), nil
+}
+
+// Cosign adds priv's signature to an already signed v2 license and returns
+// the re-encoded license.
+func Cosign(priv crypto.Signer, lic *License) (string, error) {
+	if lic.Version != 2 || lic.Signature == nil {
+		return "", fmt.Errorf("license: only signed v2 licenses can be cosigned")
+	}
+	keyID, alg := KeyID(priv.Public()), Alg(priv.Public())
+	for _, s := range lic.signatures() {
+		if s.KeyID == keyID {
+			return "", fmt.Errorf("license: key %s has already signed", keyID)
+		}
+	}
+	sig, err := signMessage(priv, lic.cosignedMessage(keyID, alg))
+	if err != nil {
+		return "", err
+	}
+	lic.Cosignatures = append(lic.Cosignatures, Cosignature{KeyID: keyID, Alg: alg, Signature: sig})
+	return lic.String(), nil
+}
+
+// signMessage returns a 64-byte signature over msg: r || s over sha256(msg)
+// for P-256, pure Ed25519 otherwise. Both are deterministic (P-256 nonces
+// follow RFC 6979), so the same key and message always give the same
+// license string.
+func signMessage(priv crypto.Signer, msg []byte) ([]byte, error) {
+	switch priv := priv.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(msg)
+		der, err := priv.Sign(nil, digest[:], crypto.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("license: signing failed: %w", err)
+		}
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &sig); err != nil {
+			return nil, fmt.Errorf("license: signing failed: %w", err)
+		}
+		return append(padScalar(sig.R.Bytes()), padScalar(sig.S.Bytes())...), nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(priv, msg), nil
+	default:
+		return nil, fmt.Errorf("license: unsupported signing key %T", priv)
+	}
+}
+
+func verifyMessage(pub crypto.PublicKey, msg, sig []byte) bool {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(msg)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, msg, sig)
+	default:
+		return false
+	}
+}
+
+// parseKeySegment splits an optional "alg:" marker off a key ID.
+func parseKeySegment(seg string) (keyID, alg string, err error) {
+	alg, keyID, found := strings.Cut(seg, ":")
+	if !found {
+		keyID, alg = seg, AlgP256
+	}
+	if keyID == "" {
+		return "", "", fmt.Errorf("%w: empty key ID", ErrMalformed)
+	}
+	if alg != AlgP256 && alg != AlgEd25519 {
+		return "", "", fmt.Errorf("%w: unknown algorithm %q", ErrMalformed, alg)
+	}
+	return keyID, alg, nil
+}
+
+// Parse decodes a license string without checking its signature. A pasted
+// "DRIFTLOCK_LICENSE_KEY=" prefix and surrounding whitespace are tolerated.
+func Parse(key string) (*License, error) {
+	key = strings.TrimSpace(key)
+	key = strings.TrimPrefix(key, "export ")
+	key = strings.TrimPrefix(key, "DRIFTLOCK_LICENSE_KEY=")
+
+	parts := strings.Split(key, ".")
+	if parts[0] == v2Prefix {
+		return parseV2(parts)
+	}
+
+	lic := &License{Version: 1, Alg: AlgP256}
+	switch len(parts) {
+	case 3:
+	case 4:
+		var err error
+		if lic.KeyID, lic.Alg, err = parseKeySegment(parts[2]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: expected TIER.EXPIRY[.KEYID].SIG, got %d part(s)", ErrMalformed, len(parts))
+	}
+	lic.Tier = parts[0]
+	if lic.Tier == "" {
+		return nil, fmt.Errorf("%w: empty tier", ErrMalformed)
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid expiry %q", ErrMalformed, parts[1])
+	}
+	lic.Expiry = expiry
+	if lic.Signature, err = decodeSignature(parts[len(parts)-1]); err != nil {
+		return nil, err
+	}
+	return lic, nil
+}
+
+func parseV2(parts []string) (*License, error) {
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("%w: expected v2.CLAIMS.KEYID.SIG, got %d part(s)", ErrMalformed, len(parts))
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid claims encoding: %v", ErrMalformed, err)
+	}
+	var claims Claims
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid claims: %v", ErrMalformed, err)
+	}
+	if claims.Version != 2 {
+		return nil, fmt.Errorf("%w: unsupported claims version %d", ErrMalformed, claims.Version)
+	}
+	if err := claims.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	segs, encSigs := strings.Split(parts[2], ","), strings.Split(parts[3], ",")
+	if len(segs) != len(encSigs) {
+		return nil, fmt.Errorf("%w: %d key ID(s) but %d signature(s)", ErrMalformed, len(segs), len(encSigs))
+	}
+	sigs := make([]Cosignature, len(segs))
+	for i := range segs {
+		keyID, alg, err := parseKeySegment(segs[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, prev := range sigs[:i] {
+			if prev.KeyID == keyID {
+				return nil, fmt.Errorf("%w: key %s signed twice", ErrMalformed, keyID)
+			}
+		}
+		sig, err := decodeSignature(encSigs[i])
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = Cosignature{KeyID: keyID, Alg: alg, Signature: sig}
+	}
+	return &License{
+		Version:      2,
+		Tier:         claims.Tier,
+		Expiry:       claims.Expiry,
+		KeyID:        sigs[0].KeyID,
+		Alg:          sigs[0].Alg,
+		Claims:       &claims,
+		Signature:    sigs[0].Signature,
+		Cosignatures: sigs[1:],
+		payload:      parts[1],
+	}, nil
+}
+
+func decodeSignature(s string) ([]byte, error) {
+	sig, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding: %v", ErrMalformed, err)
+	}
+	if len(sig) != 64 {
+		return nil, fmt.Errorf("%w: signature must be 64 bytes, got %d", ErrMalformed, len(sig))
+	}
+	return sig, nil
+}
+
+// VerifySignature checks lic against the keyring. Licenses with a key ID must
+// be signed by that key using the algorithm they declare; legacy licenses may
+// match any trusted P-256 key. Multi-signed licenses need as many trusted
+// signers as their claims require; signatures by untrusted keys are ignored,
+// but a bad signature by a trusted key fails the whole license.
+func VerifySignature(lic *License, keys Keyring) error {
+	_, err := verifySignatures(lic, keys)
+	return err
+}
+
+// verifySignatures returns the number of trusted keys that signed lic.
+func verifySignatures(lic *License, keys Keyring) (int, error) {
+	if lic.KeyID == "" {
+		for _, pub := range keys {
+			if Alg(pub) == AlgP256 && verifyMessage(pub, lic.SignedMessage(), lic.Signature) {
+				return 1, nil
+			}
+		}
+		return 0, ErrBadSignature
+	}
+
+	valid := 0
+	for _, s := range lic.signatures() {
+		pub, ok := keys[s.KeyID]
+		if !ok {
+			continue
+		}
+		if Alg(pub) != s.Alg {
+			return valid, fmt.Errorf("%w: license declares %s but key %s is %s", ErrBadSignature, s.Alg, s.KeyID, Alg(pub))
+		}
+		msg := lic.SignedMessage()
+		if lic.Version == 2 {
+			msg = lic.cosignedMessage(s.KeyID, s.Alg)
+		}
+		if !verifyMessage(pub, msg, s.Signature) {
+			return valid, ErrBadSignature
+		}
+		valid++
+	}
+	if valid == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownKey, lic.KeyID)
+	}
+	if required := lic.RequiredSigners(); valid < required {
+		return valid, fmt.Errorf("%w: %d of %d required signers are trusted", ErrInsufficientSignatures, valid, required)
+	}
+	return valid, nil
+}
+
+// DefaultMinSigners is the signer policy applied to v2 licenses when a
+// Verifier's MinSigners is nil, and by the package-level Verify: nobody
+// holding a single key can mint an ENTERPRISE license. v1 and legacy licenses
+// cannot carry cosignatures, so keys issued before v2 keep verifying.
+var DefaultMinSigners = map[string]int{"ENTERPRISE": 2}
+
+// Verifier checks licenses against a keyring and, optionally, a revocation
+// list. The revocation list must already have been verified. Machine is this
+// installation's fingerprint; host-bound licenses must match it. MinSigners
+// maps an upper-case tier to the number of trusted signers a v2 license needs,
+// whatever its own claims say; nil means DefaultMinSigners and an empty map
+// means no policy.
+//
+// ClockSkew is how far ahead of the local clock a not-before time may be and
+// still be accepted. Grace is how long after expiry Check reports StatusGrace
+// rather than StatusInvalid; Verify itself always treats expiry as hard.
+type Verifier struct {
+	Keys       Keyring
+	Revoked    *RevocationList
+	Machine    string
+	MinSigners map[string]int
+	ClockSkew  time.Duration
+	Grace      time.Duration
+}
+
+// Verify parses key, checks its signature against the keyring and
+// DefaultMinSigners, and checks its validity window at now. The parsed license
+// is returned alongside ErrExpired and ErrNotYetValid so callers can still
+// report its details.
+func Verify(key string, keys Keyring, now time.Time) (*License, error) {
+	return (&Verifier{Keys: keys}).Verify(key, now)
+}
+
+// Verify is like the package-level Verify but also rejects revoked licenses
+// and host-bound licenses activated for another machine.
+func (v *Verifier) Verify(key string, now time.Time) (*License, error) {
+	lic, err := Parse(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.VerifySignatures(lic); err != nil {
+		return lic, err
+	}
+	if v.Revoked != nil {
+		if err := v.Revoked.Check(lic); err != nil {
+			return lic, err
+		}
+	}
+	if err := checkMachine(lic, v.Machine); err != nil {
+		return lic, err
+	}
+	if lic.Claims != nil && now.Add(v.ClockSkew).Unix() < lic.Claims.NotBefore {
+		return lic, ErrNotYetValid
+	}
+	if now.Unix() >= lic.Expiry {
+		return lic, ErrExpired
+	}
+	return lic, nil
+}
+
+// VerifySignatures checks lic's signatures against the keyring and the signer
+// policy, but not revocation, machine binding or the validity window.
+func (v *Verifier) VerifySignatures(lic *License) error {
+	signers, err := verifySignatures(lic, v.Keys)
+	if err != nil {
+		return err
+	}
+	policy := v.MinSigners
+	if policy == nil {
+		policy = DefaultMinSigners
+	}
+	if need := policy[strings.ToUpper(lic.Tier)]; lic.Version == 2 && signers < need {
+		return fmt.Errorf("%w: %s licenses need %d trusted signers, got %d", ErrInsufficientSignatures, lic.Tier, need, signers)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package license
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// ErrRevoked is returned for licenses listed in a trusted revocation list.
+var ErrRevoked = errors.New("license: revoked")
+
+// Revocation records why and when one license was revoked.
+type Revocation struct {
+	Fingerprint string `json:"fingerprint"`
+	Reason      string `json:"reason"`
+	RevokedAt   int64  `json:"revoked_at"`
+}
+
+// RevocationList is a signed set of revoked license fingerprints. It is a
+// plain file so air-gapped installations can be updated by copying it over.
+// Entries are kept sorted by fingerprint so the signed encoding is canonical.
+type RevocationList struct {
+	Version   int          `json:"version"`
+	KeyID     string       `json:"key_id"`
+	IssuedAt  int64        `json:"issued_at"`
+	Entries   []Revocation `json:"entries"`
+	Signature string       `json:"signature,omitempty"`
+}
+
+// Fingerprint identifies a license by what was signed, not by its signature
+// bytes: the hex SHA-256 of the v1 signed message or of "v2.CLAIMS". An
+// ECDSA signature can be rewritten (s to n-s) and a v2 license's extra
+// cosignatures reordered or dropped while it still verifies, so hashing the
+// full encoding would let such a copy slip past a revocation. Licenses with
+// identical signed content share a fingerprint.
+func Fingerprint(lic *License) string {
+	sum := sha256.Sum256(lic.signedContent())
+	return hex.EncodeToString(sum[:])
+}
+
+// Revoke adds or replaces the entry for fingerprint. The list must be signed
+// again afterwards.
+func (rl *RevocationList) Revoke(fingerprint, reason string, at int64) {
+	for i, e := range rl.Entries {
+		if e.Fingerprint == fingerprint {
+			rl.Entries[i] = Revocation{Fingerprint: fingerprint, Reason: reason, RevokedAt: at}
+			return
+		}
+	}
+	rl.Entries = append(rl.Entries, Revocation{Fingerprint: fingerprint, Reason: reason, RevokedAt: at})
+	sort.Slice(rl.Entries, func(i, j int) bool { return rl.Entries[i].Fingerprint < rl.Entries[j].Fingerprint })
+}
+
+// Lookup returns the revocation entry for fingerprint, if any.
+func (rl *RevocationList) Lookup(fingerprint string) (Revocation, bool) {
+	for _, e := range rl.Entries {
+		if e.Fingerprint == fingerprint {
+			return e, true
+		}
+	}
+	return Revocation{}, false
+}
+
+func (rl *RevocationList) signedMessage() ([]byte, error) {
+	unsigned := *rl
+	unsigned.Signature = ""
+	if unsigned.Entries == nil {
+		unsigned.Entries = []Revocation{}
+	}
+	return json.Marshal(unsigned)
+}
+
+// SignRevocationList stamps rl with the signer's key ID and issue time and
+// signs it.
+func SignRevocationList(priv crypto.Signer, rl *RevocationList, issuedAt int64) error {
+	rl.Version = 1
+	rl.KeyID = KeyID(priv.Public())
+	rl.IssuedAt = issuedAt
+	msg, err := rl.signedMessage()
+	if err != nil {
+		return err
+	}
+	sig, err := signMessage(priv, msg)
+	if err != nil {
+		return err
+	}
+	rl.Signature = base64.RawStdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// Verify checks the list was signed by a key in the keyring.
+func (rl *RevocationList) Verify(keys Keyring) error {
+	if rl.Version != 1 {
+		return fmt.Errorf("%w: unsupported revocation list version %d", ErrMalformed, rl.Version)
+	}
+	pub, ok := keys[rl.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: revocation list signed by %s", ErrUnknownKey, rl.KeyID)
+	}
+	sig, err := decodeSignature(rl.Signature)
+	if err != nil {
+		return err
+	}
+	msg, err := rl.signedMessage()
+	if err != nil {
+		return err
+	}
+	if !verifyMessage(pub, msg, sig) {
+		return fmt.Errorf("%w: revocation list", ErrBadSignature)
+	}
+	return nil
+}
+
+// Check returns ErrRevoked if lic's fingerprint is on the list.
+func (rl *RevocationList) Check(lic *License) error {
+	if e, ok := rl.Lookup(Fingerprint(lic)); ok {
+		return fmt.Errorf("%w: %s (at %s)", ErrRevoked, e.Reason, time.Unix(e.RevokedAt, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ReadRevocationList loads a revocation list file without verifying it.
+func ReadRevocationList(path string) (*RevocationList, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rl RevocationList
+	if err := json.Unmarshal(b, &rl); err != nil {
+		return nil, fmt.Errorf("revocation list %s: %w", path, err)
+	}
+	return &rl, nil
+}
+
+// WriteRevocationList saves rl to path.
+func WriteRevocationList(path string, rl *RevocationList) error {
+	b, err := json.MarshalIndent(rl, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
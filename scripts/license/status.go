@@ -0,0 +1,57 @@
+package license
+
+import (
+	"errors"
+	"time"
+)
+
+// Status is the tri-state outcome of Verifier.Check.
+type Status int
+
+const (
+	// StatusInvalid means the license must not be honoured.
+	StatusInvalid Status = iota
+	// StatusValid means the license is within its validity window.
+	StatusValid
+	// StatusGrace means the license has expired but is within the
+	// verifier's grace period: keep working, but warn.
+	StatusGrace
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusValid:
+		return "valid"
+	case StatusGrace:
+		return "grace"
+	default:
+		return "invalid"
+	}
+}
+
+// Result is what Check reports about a license. Err explains an invalid
+// result and is ErrExpired during grace; License is nil only if the key could
+// not be parsed.
+type Result struct {
+	Status    Status
+	License   *License
+	Err       error
+	GraceEnds time.Time // set for StatusGrace
+}
+
+// Check is Verify with the grace period applied: an expired license whose
+// other checks all pass is reported as StatusGrace until Grace has elapsed
+// since its expiry.
+func (v *Verifier) Check(key string, now time.Time) Result {
+	lic, err := v.Verify(key, now)
+	switch {
+	case err == nil:
+		return Result{Status: StatusValid, License: lic}
+	case errors.Is(err, ErrExpired) && v.Grace > 0:
+		ends := time.Unix(lic.Expiry, 0).Add(v.Grace)
+		if now.Before(ends) {
+			return Result{Status: StatusGrace, License: lic, Err: err, GraceEnds: ends}
+		}
+	}
+	return Result{Status: StatusInvalid, License: lic, Err: err}
+}
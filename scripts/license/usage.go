@@ -0,0 +1,277 @@
+package license
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// UsageRow is one day of counters for one stream, in the shape of the API's
+// usage_metrics table. The AI cost columns are DECIMAL(10,6) there and are
+// carried as strings so no precision is lost on the way.
+type UsageRow struct {
+	TenantID        string `json:"tenant_id"`
+	StreamID        string `json:"stream_id"`
+	Date            string `json:"date"` // YYYY-MM-DD
+	EventCount      int64  `json:"event_count"`
+	APIRequestCount int64  `json:"api_request_count"`
+	AnomalyCount    int64  `json:"anomaly_count"`
+	AICallsCount    int64  `json:"ai_calls_count"`
+	AIInputTokens   int64  `json:"ai_input_tokens"`
+	AIOutputTokens  int64  `json:"ai_output_tokens"`
+	AICostUSD       string `json:"ai_cost_usd"`
+	AIChargeUSD     string `json:"ai_charge_usd"`
+}
+
+var decimalPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// Validate reports whether the row is well formed.
+func (u *UsageRow) Validate() error {
+	if u.TenantID == "" || u.StreamID == "" {
+		return fmt.Errorf("usage: tenant_id and stream_id are required")
+	}
+	if _, err := time.Parse(time.DateOnly, u.Date); err != nil {
+		return fmt.Errorf("usage: invalid date %q", u.Date)
+	}
+	for _, n := range []int64{u.EventCount, u.APIRequestCount, u.AnomalyCount, u.AICallsCount, u.AIInputTokens, u.AIOutputTokens} {
+		if n < 0 {
+			return fmt.Errorf("usage: %s %s: counters must not be negative", u.StreamID, u.Date)
+		}
+	}
+	for _, d := range []string{u.AICostUSD, u.AIChargeUSD} {
+		if !decimalPattern.MatchString(d) {
+			return fmt.Errorf("usage: %s %s: invalid decimal %q", u.StreamID, u.Date, d)
+		}
+	}
+	return nil
+}
+
+// UsageReport is a usage attestation from a self-hosted installation. It is
+// signed by the installation key named in the license's install_key claim,
+// so a report can only come from the installation the license was
+// activated on. Rows are sorted by date and stream so the encoding is
+// canonical; the signature covers the JSON with Signature empty.
+type UsageReport struct {
+	Version     int        `json:"version"`
+	License     string     `json:"license"`
+	KeyID       string     `json:"key_id"`
+	PeriodStart string     `json:"period_start"`
+	PeriodEnd   string     `json:"period_end"`
+	GeneratedAt int64      `json:"generated_at"`
+	Rows        []UsageRow `json:"rows"`
+	Signature   string     `json:"signature,omitempty"`
+}
+
+func (r *UsageReport) signedMessage() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	if unsigned.Rows == nil {
+		unsigned.Rows = []UsageRow{}
+	}
+	return json.Marshal(unsigned)
+}
+
+// SignUsageReport sorts r's rows, stamps it and signs it with the
+// installation key priv.
+func SignUsageReport(priv crypto.Signer, r *UsageReport, generatedAt int64) error {
+	for i := range r.Rows {
+		if err := r.Rows[i].Validate(); err != nil {
+			return err
+		}
+	}
+	sort.Slice(r.Rows, func(i, j int) bool {
+		if r.Rows[i].Date != r.Rows[j].Date {
+			return r.Rows[i].Date < r.Rows[j].Date
+		}
+		return r.Rows[i].StreamID < r.Rows[j].StreamID
+	})
+	r.Version = 1
+	r.KeyID = KeyID(priv.Public())
+	r.GeneratedAt = generatedAt
+	msg, err := r.signedMessage()
+	if err != nil {
+		return err
+	}
+	sig, err := signMessage(priv, msg)
+	if err != nil {
+		return err
+	}
+	r.Signature = base64.RawStdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// InstallKey returns the installation public key a license is bound to.
+func InstallKey(lic *License) (crypto.PublicKey, error) {
+	if lic.Claims == nil || lic.Claims.InstallKey == "" {
+		return nil, errors.New("license has no installation key; activate it with one first")
+	}
+	return ParsePublicKeyHex(lic.Claims.InstallKey)
+}
+
+// Verify checks the embedded license against the issuer keyring, then the
+// report signature against the license's installation key, and returns the
+// license. Expired licenses are accepted: their last month still needs
+// reconciling.
+func (r *UsageReport) Verify(keys Keyring) (*License, error) {
+	if r.Version != 1 {
+		return nil, fmt.Errorf("%w: unsupported usage report version %d", ErrMalformed, r.Version)
+	}
+	lic, err := Parse(r.License)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifySignature(lic, keys); err != nil {
+		return lic, fmt.Errorf("usage report license: %w", err)
+	}
+	pub, err := InstallKey(lic)
+	if err != nil {
+		return lic, fmt.Errorf("%w: %v", ErrUnknownKey, err)
+	}
+	if KeyID(pub) != r.KeyID {
+		return lic, fmt.Errorf("%w: report signed by %s, license names installation key %s", ErrUnknownKey, r.KeyID, KeyID(pub))
+	}
+	sig, err := decodeSignature(r.Signature)
+	if err != nil {
+		return lic, err
+	}
+	msg, err := r.signedMessage()
+	if err != nil {
+		return lic, err
+	}
+	if !verifyMessage(pub, msg, sig) {
+		return lic, fmt.Errorf("%w: usage report", ErrBadSignature)
+	}
+	for i := range r.Rows {
+		if err := r.Rows[i].Validate(); err != nil {
+			return lic, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+	}
+	return lic, nil
+}
+
+// ReadUsageReport loads a usage report file without verifying it.
+func ReadUsageReport(path string) (*UsageReport, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r UsageReport
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("usage report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// WriteUsageReport saves r to path.
+func WriteUsageReport(path string, r *UsageReport) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// MonthlyUsage totals one calendar month of a report against the license.
+type MonthlyUsage struct {
+	Month       string `json:"month"` // YYYY-MM
+	Events      int64  `json:"events"`
+	EventQuota  int64  `json:"event_quota"` // 0 = unlimited
+	APIRequests int64  `json:"api_requests"`
+	Anomalies   int64  `json:"anomalies"`
+	AICalls     int64  `json:"ai_calls"`
+	AIChargeUSD string `json:"ai_charge_usd"`
+	Streams     int    `json:"streams"`
+	StreamLimit int64  `json:"stream_limit"` // 0 = unlimited
+	OverQuota   bool   `json:"over_quota"`
+}
+
+// Reconciliation is the issuer's view of a verified usage report.
+type Reconciliation struct {
+	TenantID string         `json:"tenant_id"`
+	Months   []MonthlyUsage `json:"months"`
+	Findings []string       `json:"findings"`
+}
+
+// OverQuota reports whether any month exceeded the license or any finding
+// was raised.
+func (rc *Reconciliation) OverQuota() bool {
+	for _, m := range rc.Months {
+		if m.OverQuota {
+			return true
+		}
+	}
+	return len(rc.Findings) > 0
+}
+
+// Reconcile totals the report per month and checks it against the claims:
+// event quota, stream limit, the ai_explanations feature, the tenant and the
+// license's validity window. Repeated (date, stream) rows and rows outside the
+// report's own period are findings too and are left out of the totals, so a
+// day cannot be counted twice or slipped in from another period.
+func Reconcile(r *UsageReport, claims *Claims) *Reconciliation {
+	rc := &Reconciliation{TenantID: claims.TenantID, Findings: []string{}}
+	start, errStart := time.Parse(time.DateOnly, r.PeriodStart)
+	end, errEnd := time.Parse(time.DateOnly, r.PeriodEnd)
+	if errStart != nil || errEnd != nil || end.Before(start) {
+		rc.Findings = append(rc.Findings, fmt.Sprintf("invalid report period %q to %q", r.PeriodStart, r.PeriodEnd))
+	}
+	months := map[string]*MonthlyUsage{}
+	streams := map[string]map[string]bool{}
+	charges := map[string]*big.Rat{}
+	seen := map[[2]string]bool{}
+	for _, row := range r.Rows {
+		key := [2]string{row.Date, row.StreamID}
+		if seen[key] {
+			rc.Findings = append(rc.Findings, fmt.Sprintf("%s %s: duplicate row, not counted", row.Date, row.StreamID))
+			continue
+		}
+		seen[key] = true
+		day, _ := time.Parse(time.DateOnly, row.Date)
+		if errStart == nil && errEnd == nil && (day.Before(start) || day.After(end)) {
+			rc.Findings = append(rc.Findings, fmt.Sprintf("%s %s: outside the report period %s to %s, not counted", row.Date, row.StreamID, r.PeriodStart, r.PeriodEnd))
+			continue
+		}
+		if row.TenantID != claims.TenantID {
+			rc.Findings = append(rc.Findings, fmt.Sprintf("%s %s: tenant %s is not the licensed tenant", row.Date, row.StreamID, row.TenantID))
+		}
+		// A day counts if any part of it falls inside the license window.
+		if day.Add(24*time.Hour).Unix() <= claims.NotBefore || day.Unix() >= claims.Expiry {
+			rc.Findings = append(rc.Findings, fmt.Sprintf("%s %s: usage outside the license validity window", row.Date, row.StreamID))
+		}
+		if row.AICallsCount > 0 && !claims.HasFeature("ai_explanations") {
+			rc.Findings = append(rc.Findings, fmt.Sprintf("%s %s: %d AI call(s) without the ai_explanations feature", row.Date, row.StreamID, row.AICallsCount))
+		}
+		month := row.Date[:7]
+		m, ok := months[month]
+		if !ok {
+			m = &MonthlyUsage{Month: month, EventQuota: claims.EventsPerMonth, StreamLimit: claims.StreamLimit}
+			months[month] = m
+			streams[month] = map[string]bool{}
+			charges[month] = new(big.Rat)
+		}
+		m.Events += row.EventCount
+		m.APIRequests += row.APIRequestCount
+		m.Anomalies += row.AnomalyCount
+		m.AICalls += row.AICallsCount
+		streams[month][row.StreamID] = true
+		if charge, ok := new(big.Rat).SetString(row.AIChargeUSD); ok {
+			charges[month].Add(charges[month], charge)
+		}
+	}
+	for month, m := range months {
+		m.Streams = len(streams[month])
+		m.AIChargeUSD = charges[month].FloatString(6)
+		m.OverQuota = (m.EventQuota > 0 && m.Events > m.EventQuota) ||
+			(m.StreamLimit > 0 && int64(m.Streams) > m.StreamLimit)
+		rc.Months = append(rc.Months, *m)
+	}
+	sort.Slice(rc.Months, func(i, j int) bool { return rc.Months[i].Month < rc.Months[j].Month })
+	return rc
+}
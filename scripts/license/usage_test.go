@@ -0,0 +1,80 @@
+package license
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testUsageReport(rows ...UsageRow) (*UsageReport, *Claims) {
+	for i := range rows {
+		rows[i].TenantID = "acme"
+		rows[i].AIChargeUSD = "0"
+	}
+	report := &UsageReport{PeriodStart: "2026-03-01", PeriodEnd: "2026-03-31", Rows: rows}
+	claims := &Claims{
+		TenantID:       "acme",
+		EventsPerMonth: 1000,
+		NotBefore:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		Expiry:         time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+	}
+	return report, claims
+}
+
+func wantFinding(t *testing.T, rc *Reconciliation, substr string) {
+	t.Helper()
+	if !rc.OverQuota() {
+		t.Errorf("OverQuota() = false with findings %v", rc.Findings)
+	}
+	for _, f := range rc.Findings {
+		if strings.Contains(f, substr) {
+			return
+		}
+	}
+	t.Errorf("findings %v, want one containing %q", rc.Findings, substr)
+}
+
+func TestReconcileClean(t *testing.T) {
+	rc := Reconcile(testUsageReport(
+		UsageRow{StreamID: "s1", Date: "2026-03-01", EventCount: 400},
+		UsageRow{StreamID: "s2", Date: "2026-03-01", EventCount: 400},
+	))
+	if rc.OverQuota() || len(rc.Findings) != 0 {
+		t.Fatalf("findings %v", rc.Findings)
+	}
+	if len(rc.Months) != 1 || rc.Months[0].Events != 800 {
+		t.Errorf("months = %+v", rc.Months)
+	}
+}
+
+func TestReconcileRejectsDuplicateRows(t *testing.T) {
+	// Twice 600 would be over quota; once is not, so the finding is what
+	// fails the report.
+	rc := Reconcile(testUsageReport(
+		UsageRow{StreamID: "s1", Date: "2026-03-02", EventCount: 600},
+		UsageRow{StreamID: "s1", Date: "2026-03-02", EventCount: 600},
+	))
+	wantFinding(t, rc, "2026-03-02 s1: duplicate row")
+	if len(rc.Months) != 1 || rc.Months[0].Events != 600 {
+		t.Errorf("months = %+v, want the duplicate left out", rc.Months)
+	}
+}
+
+func TestReconcileRejectsRowsOutsidePeriod(t *testing.T) {
+	for _, date := range []string{"2026-02-28", "2026-04-01"} {
+		rc := Reconcile(testUsageReport(
+			UsageRow{StreamID: "s1", Date: "2026-03-31", EventCount: 10},
+			UsageRow{StreamID: "s1", Date: date, EventCount: 10},
+		))
+		wantFinding(t, rc, date+" s1: outside the report period")
+		if len(rc.Months) != 1 || rc.Months[0].Month != "2026-03" {
+			t.Errorf("%s: months = %+v, want only 2026-03", date, rc.Months)
+		}
+	}
+}
+
+func TestReconcileRejectsInvalidPeriod(t *testing.T) {
+	report, claims := testUsageReport(UsageRow{StreamID: "s1", Date: "2026-03-02"})
+	report.PeriodStart, report.PeriodEnd = "2026-03-31", "2026-03-01"
+	wantFinding(t, Reconcile(report, claims), "invalid report period")
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// csvOptions control how CSV rows become flat records.
+type csvOptions struct {
+	tsField string
+	times   timeConfig
+	preset  *preset
+	rng     *rand.Rand        // noise for presets, seeded by --seed
+	types   map[string]string // column type hints; see types.go
+
+	labelCols []string     // columns moved to the sidecar
+	sidecar   *labelWriter // nil unless --labels is set
+	sel       *selection   // filters and sampling; nil keeps every row
+	inj       *injector    // synthetic anomalies; nil injects none
+
+	parsers map[string]*timeParser // per timestamp column, made by parser
+}
+
+// parser returns the timestamp parser for col, which keeps the layout it
+// resolved on the column's first value.
+func (o csvOptions) parser(col string) *timeParser {
+	p, ok := o.parsers[col]
+	if !ok {
+		p = o.times.parser()
+		o.parsers[col] = p
+	}
+	return p
+}
+
+// csvRecords reads CSV rows as coerced, normalised records.
+type csvRecords struct {
+	read    func() ([]string, error)
+	headers []string
+	opts    csvOptions
+	row     int
+
+	presetTimes *timeParser // input timestamps a preset reads itself
+
+	buffered []map[string]interface{} // rows of a sorted or tailed preset
+	loaded   bool
+}
+
+func newCSVRecords(r io.Reader, opts csvOptions) (*csvRecords, error) {
+	c := &csvRecords{opts: opts}
+	if opts.preset != nil {
+		cfg := opts.times
+		cfg.layout = layoutAuto
+		c.presetTimes = cfg.parser()
+	}
+	if p := opts.preset; p != nil && p.whitespace {
+		sc := bufio.NewScanner(r)
+		c.read = func() ([]string, error) {
+			for sc.Scan() {
+				if fields := strings.Fields(sc.Text()); len(fields) > 0 {
+					return fields, nil
+				}
+			}
+			if err := sc.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+	} else {
+		cr := csv.NewReader(r)
+		cr.TrimLeadingSpace = true
+		c.read = cr.Read
+	}
+
+	if p := opts.preset; p != nil && p.columns != nil {
+		c.headers = p.columns
+		return c, nil
+	}
+	headers, err := c.read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	c.headers = headers
+	return c, nil
+}
+
+// fields are the columns next returns: the preset's output columns if it
+// reshapes rows, otherwise the input header plus any columns it adds.
+func (c *csvRecords) fields() []string {
+	p := c.opts.preset
+	if p == nil {
+		return c.headers
+	}
+	if p.fields != nil {
+		return p.fields
+	}
+	return append(append([]string{}, c.headers...), p.adds...)
+}
+
+// next returns the next record, or io.EOF.
+func (c *csvRecords) next() (map[string]interface{}, error) {
+	if p := c.opts.preset; p != nil && (p.sorted || p.tail > 0) {
+		return c.nextBuffered(p)
+	}
+	return c.nextStreamed()
+}
+
+func (c *csvRecords) nextStreamed() (map[string]interface{}, error) {
+	for {
+		event, err := c.nextRow()
+		if err != nil || event != nil {
+			return event, err
+		}
+	}
+}
+
+// nextBuffered reads the whole input on the first call, then replays it
+// sorted by timestamp and cut to the preset's tail, as the Python scripts did
+// with the whole DataFrame in memory.
+func (c *csvRecords) nextBuffered(p *preset) (map[string]interface{}, error) {
+	if !c.loaded {
+		var times []time.Time
+		for {
+			rec, err := c.nextStreamed()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			ts, err := time.Parse(time.RFC3339Nano, text(rec["timestamp"]))
+			if err != nil && p.sorted {
+				return nil, fmt.Errorf("row %d: --dataset sorts by timestamp, but it is missing or unreadable", c.row)
+			}
+			c.buffered = append(c.buffered, rec)
+			times = append(times, ts)
+		}
+		if p.sorted {
+			sort.Stable(byTime{c.buffered, times})
+		}
+		if p.tail > 0 && len(c.buffered) > p.tail {
+			c.buffered = c.buffered[len(c.buffered)-p.tail:]
+		}
+		c.loaded = true
+	}
+	if len(c.buffered) == 0 {
+		return nil, io.EOF
+	}
+	rec := c.buffered[0]
+	c.buffered = c.buffered[1:]
+	return rec, nil
+}
+
+// byTime sorts records by their parsed timestamps.
+type byTime struct {
+	recs  []map[string]interface{}
+	times []time.Time
+}
+
+func (b byTime) Len() int           { return len(b.recs) }
+func (b byTime) Less(i, j int) bool { return b.times[i].Before(b.times[j]) }
+func (b byTime) Swap(i, j int) {
+	b.recs[i], b.recs[j] = b.recs[j], b.recs[i]
+	b.times[i], b.times[j] = b.times[j], b.times[i]
+}
+
+// nextRow reads one row; it returns a nil record if the preset skipped it.
+func (c *csvRecords) nextRow() (map[string]interface{}, error) {
+	record, err := c.read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	c.row++
+	if err != nil {
+		return nil, fmt.Errorf("read row %d: %w", c.row, err)
+	}
+	if len(record) != len(c.headers) {
+		return nil, fmt.Errorf("row %d: header/data length mismatch (%d vs %d)", c.row, len(c.headers), len(record))
+	}
+
+	event := map[string]interface{}{}
+	for i, h := range c.headers {
+		hint, ok := c.opts.types[h]
+		if !ok {
+			event[h] = coerce(record[i])
+			continue
+		}
+		v, err := convertValue(record[i], hint, c.opts.parser(h))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: column %q: %w", c.row, h, err)
+		}
+		event[h] = v
+	}
+
+	if p := c.opts.preset; p != nil {
+		event, err = p.transform(presetRow{rec: event, index: c.row - 1, rng: c.opts.rng, times: c.presetTimes})
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", c.row, err)
+		}
+		if event == nil {
+			return nil, nil
+		}
+	}
+
+	if err := normalizeTimestamp(event, c.opts); err != nil {
+		return nil, fmt.Errorf("row %d: %w", c.row, err)
+	}
+	return event, nil
+}
+
+// normalizeTimestamp parses the configured timestamp field and stores it as
+// RFC 3339 in UTC under "timestamp". An empty value leaves the event without
+// one; a value that does not parse is an error rather than a silently
+// reordered event.
+func normalizeTimestamp(event map[string]interface{}, opts csvOptions) error {
+	raw, ok := event[opts.tsField]
+	if !ok || raw == nil || raw == "" {
+		return nil
+	}
+	if s, ok := raw.(string); ok && opts.types[opts.tsField] == typeTimestamp {
+		// Already parsed and formatted by the type hint.
+		event["timestamp"] = s
+		return nil
+	}
+	ts, err := opts.parser(opts.tsField).parse(raw)
+	if err != nil {
+		return fmt.Errorf("timestamp %q: %w", opts.tsField, err)
+	}
+	event["timestamp"] = ts.Format(time.RFC3339Nano)
+	return nil
+}
+
+// jsonlRecords reads one JSON object per line. Numbers are kept as
+// json.Number so large identifiers survive the round trip.
+type jsonlRecords struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func newJSONLRecords(r io.Reader) *jsonlRecords {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxRequestBytes)
+	return &jsonlRecords{sc: sc}
+}
+
+func (j *jsonlRecords) next() (map[string]interface{}, error) {
+	for j.sc.Scan() {
+		j.line++
+		if strings.TrimSpace(j.sc.Text()) == "" {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(j.sc.Text()))
+		dec.UseNumber()
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("line %d: %w", j.line, err)
+		}
+		return obj, nil
+	}
+	if err := j.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func convertCSV(input, output string, limit int, opts csvOptions) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records, err := newCSVRecords(f, opts)
+	if err != nil {
+		return err
+	}
+	next := opts.inj.wrap(opts.sel.wrap(records.next))
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	row := 0
+	for {
+		if limit > 0 && row >= limit {
+			break
+		}
+
+		event, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if opts.sidecar != nil {
+			labels := splitLabels(event, opts.labelCols)
+			if err := opts.sidecar.add(int64(row+1), "", labels, opts.inj.mark()); err != nil {
+				return err
+			}
+		}
+
+		b, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("marshal row %d: %w", row+1, err)
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+		row++
+	}
+
+	return nil
+}
+
+func copyNDJSON(input, output string, limit int) error {
+	in, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	sc := bufio.NewScanner(in)
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	line := 0
+	for sc.Scan() {
+		if limit > 0 && line >= limit {
+			break
+		}
+		if _, err := w.Write(sc.Bytes()); err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+		line++
+	}
+	return sc.Err()
+}
+
+// coerce guesses a type for an unhinted CSV value. Numbers are kept as
+// json.Number so the emitted text is exactly the input text: 19-digit card
+// numbers are not rounded through float64 and "4.50" stays "4.50". Text that
+// is not a JSON number, such as "00123", stays a string.
+func coerce(val string) interface{} {
+	if val == "" {
+		return val
+	}
+	if jsonNumber.MatchString(val) {
+		return json.Number(val)
+	}
+	lower := strings.ToLower(val)
+	if lower == "true" || lower == "false" {
+		return lower == "true"
+	}
+	// Unquote if it looks like a quoted string with doubled quotes (CSV)
+	if strings.HasPrefix(val, "\"") && strings.HasSuffix(val, "\"") {
+		return strings.Trim(val, "\"")
+	}
+	return val
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Limits from docs/user-guide/api/endpoints/detect.md.
+const (
+	maxEventsPerRequest = 256
+	maxRequestBytes     = 10 << 20
+)
+
+// detectEvent is one element of a /v1/detect request's events array.
+type detectEvent struct {
+	Timestamp      string                 `json:"timestamp,omitempty"`
+	Type           string                 `json:"type,omitempty"`
+	Body           map[string]interface{} `json:"body"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"`
+	Sequence       int64                  `json:"sequence"` // always set; 0 is a valid source value
+}
+
+// batchOptions bound each request body.
+type batchOptions struct {
+	streamID  string
+	batchSize int
+	maxBytes  int
+}
+
+// requestWriter groups encoded events into request bodies, one per line,
+// starting a new request before either the event count or the encoded size
+// limit would be exceeded.
+type requestWriter struct {
+	w        *bufio.Writer
+	opts     batchOptions
+	header   []byte
+	pending  [][]byte
+	size     int
+	requests int
+}
+
+func newRequestWriter(w io.Writer, opts batchOptions) (*requestWriter, error) {
+	header := []byte(`{"events":[`)
+	if opts.streamID != "" {
+		id, err := json.Marshal(opts.streamID)
+		if err != nil {
+			return nil, err
+		}
+		header = []byte(`{"stream_id":` + string(id) + `,"events":[`)
+	}
+	return &requestWriter{w: bufio.NewWriter(w), opts: opts, header: header}, nil
+}
+
+// bodySize is the encoded size of a request holding the pending events plus
+// one more of n bytes.
+func (rw *requestWriter) bodySize(n int) int {
+	return len(rw.header) + rw.size + len(rw.pending) + n + len("]}")
+}
+
+func (rw *requestWriter) add(ev detectEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event %d: %w", ev.Sequence, err)
+	}
+	if len(rw.pending) > 0 && (len(rw.pending) >= rw.opts.batchSize || rw.bodySize(len(b)) > rw.opts.maxBytes) {
+		if err := rw.flush(); err != nil {
+			return err
+		}
+	}
+	if rw.bodySize(len(b)) > rw.opts.maxBytes {
+		return fmt.Errorf("event %d alone encodes to %d bytes, over the %d byte request limit", ev.Sequence, len(b), rw.opts.maxBytes)
+	}
+	rw.pending = append(rw.pending, b)
+	rw.size += len(b)
+	return nil
+}
+
+func (rw *requestWriter) flush() error {
+	if len(rw.pending) == 0 {
+		return nil
+	}
+	rw.w.Write(rw.header)
+	for i, b := range rw.pending {
+		if i > 0 {
+			rw.w.WriteByte(',')
+		}
+		rw.w.Write(b)
+	}
+	rw.w.WriteString("]}\n")
+	rw.pending, rw.size = rw.pending[:0], 0
+	rw.requests++
+	return rw.w.Flush()
+}
+
+// eventLineWriter writes one event object per line.
+type eventLineWriter struct {
+	w *bufio.Writer
+}
+
+func (lw *eventLineWriter) add(ev detectEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event %d: %w", ev.Sequence, err)
+	}
+	lw.w.Write(b)
+	return lw.w.WriteByte('\n')
+}
+
+func (lw *eventLineWriter) flush() error { return lw.w.Flush() }
+
+// eventSink receives mapped events in input order.
+type eventSink interface {
+	add(detectEvent) error
+	flush() error
+}
+
+// convertEvents reads CSV or JSONL input, maps each record through m and
+// hands the events to the sink made by newSink.
+func convertEvents(input, ext, output string, limit int, opts csvOptions, m *mapping, newSink func(io.Writer) (eventSink, error)) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var next func() (map[string]interface{}, error)
+	switch ext {
+	case ".csv":
+		records, err := newCSVRecords(f, opts)
+		if err != nil {
+			return err
+		}
+		if err := m.checkColumns(records.fields()); err != nil {
+			return err
+		}
+		next = records.next
+	case ".jsonl", ".ndjson":
+		records := newJSONLRecords(f)
+		next = func() (map[string]interface{}, error) {
+			rec, err := records.next()
+			if err != nil {
+				return nil, err
+			}
+			if err := applyTypes(rec, opts); err != nil {
+				return nil, fmt.Errorf("line %d: %w", records.line, err)
+			}
+			if err := normalizeTimestamp(rec, opts); err != nil {
+				return nil, fmt.Errorf("line %d: %w", records.line, err)
+			}
+			return rec, nil
+		}
+	default:
+		return fmt.Errorf("unsupported input extension %q (expected .csv or .jsonl/.ndjson)", ext)
+	}
+
+	next = opts.inj.wrap(opts.sel.wrap(next))
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	sink, err := newSink(out)
+	if err != nil {
+		return err
+	}
+	for seq := int64(1); limit <= 0 || seq <= int64(limit); seq++ {
+		rec, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var labels map[string]interface{}
+		if opts.sidecar != nil {
+			labels = splitLabels(rec, opts.labelCols)
+		}
+		ev, err := m.toEvent(rec, seq)
+		if err != nil {
+			return err
+		}
+		if err := sink.add(ev); err != nil {
+			return err
+		}
+		if opts.sidecar != nil {
+			if err := opts.sidecar.add(ev.Sequence, ev.IdempotencyKey, labels, opts.inj.mark()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := sink.flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
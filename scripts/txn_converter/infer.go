@@ -0,0 +1,399 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// maxDistinct caps the values tracked per column for cardinality.
+const maxDistinct = 10000
+
+var (
+	labelName     = regexp.MustCompile(`(?i)(^|_)(label|is_fraud|fraud|anomal(y|ous)?|attack|outlier|target|class|malicious|y)($|_)`)
+	timestampName = regexp.MustCompile(`(?i)time|date|(^|_)ts($|_)`)
+	idName        = regexp.MustCompile(`(?i)(^|_)(id|uuid|guid|key|num|number)$`)
+	idNameStrong  = regexp.MustCompile(`(?i)(^|_)(id|uuid|guid)$`)
+)
+
+// columnStats accumulates what --infer reports about one column.
+type columnStats struct {
+	name     string
+	nulls    int
+	values   int
+	distinct map[string]int
+	overflow bool // more than maxDistinct distinct values
+
+	// kinds counts values that could be read as each type.
+	ints, decimals, bools, objects int
+	layouts                        map[string]int
+
+	minNum, maxNum   *big.Float
+	minText, maxText string // input text of minNum and maxNum
+	minStr, maxStr   string
+}
+
+func newColumnStats(name string) *columnStats {
+	return &columnStats{name: name, distinct: map[string]int{}, layouts: map[string]int{}}
+}
+
+func isNull(s string) bool {
+	switch strings.ToLower(s) {
+	case "", "null", "na", "n/a", "none":
+		return true
+	}
+	return false
+}
+
+// observe records one value: raw CSV text, or a decoded JSONL value.
+func (c *columnStats) observe(v interface{}) {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		c.nulls++
+		return
+	case string:
+		s = strings.TrimSpace(v)
+		if isNull(s) {
+			c.nulls++
+			return
+		}
+		if (strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")) && json.Valid([]byte(s)) {
+			c.objects++
+		}
+	case json.Number:
+		s = v.String()
+	case bool:
+		s = fmt.Sprint(v)
+	default:
+		b, _ := json.Marshal(v)
+		s = string(b)
+		c.objects++
+	}
+	c.values++
+
+	if !c.overflow {
+		c.distinct[s]++
+		if len(c.distinct) > maxDistinct {
+			c.overflow = true
+			c.distinct = nil
+		}
+	}
+
+	lower := strings.ToLower(s)
+	if lower == "true" || lower == "false" {
+		c.bools++
+	}
+	if jsonNumber.MatchString(s) {
+		c.decimals++
+		if !strings.ContainsAny(s, ".eE") {
+			c.ints++
+		}
+		f, _, err := big.ParseFloat(s, 10, 128, big.ToNearestEven)
+		if err == nil {
+			if c.minNum == nil || f.Cmp(c.minNum) < 0 {
+				c.minNum, c.minText = f, s
+			}
+			if c.maxNum == nil || f.Cmp(c.maxNum) > 0 {
+				c.maxNum, c.maxText = f, s
+			}
+		}
+	}
+	for _, layout := range commonLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			c.layouts[layout]++
+		}
+	}
+	if jsonNumber.MatchString(s) {
+		for _, unit := range []string{"s", "ms", "us", "ns"} {
+			if ts, err := fromUnits(s, epochUnits[unit], time.Unix(0, 0)); err == nil && ts.After(epochMin) && ts.Before(epochMax) {
+				c.layouts["epoch_"+unit]++
+			}
+		}
+	}
+	if c.values == 1 || s < c.minStr {
+		c.minStr = s
+	}
+	if c.values == 1 || s > c.maxStr {
+		c.maxStr = s
+	}
+}
+
+// kind is the most specific type every non-null value fits.
+func (c *columnStats) kind() string {
+	switch {
+	case c.values == 0:
+		return typeString
+	case c.bools == c.values:
+		return typeBool
+	case c.ints == c.values:
+		return typeInt
+	case c.decimals == c.values:
+		return typeDecimal
+	case len(c.timeLayouts()) > 0:
+		return typeTimestamp
+	case c.objects == c.values:
+		return typeJSON
+	}
+	return typeString
+}
+
+// Unix times outside this range are more likely amounts or identifiers.
+var (
+	epochMin = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	epochMax = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// timeLayouts are the common layouts every non-null value parses under. A
+// numeric column is only offered as Unix time when its name suggests a time.
+func (c *columnStats) timeLayouts() []string {
+	var out []string
+	for _, layout := range commonLayouts {
+		if c.values > 0 && c.layouts[layout] == c.values {
+			out = append(out, layout)
+		}
+	}
+	if timestampName.MatchString(c.name) {
+		for _, unit := range []string{"s", "ms", "us", "ns"} {
+			if c.values > 0 && c.layouts["epoch_"+unit] == c.values {
+				out = append(out, "epoch_"+unit)
+			}
+		}
+	}
+	return out
+}
+
+func (c *columnStats) cardinality() string {
+	if c.overflow {
+		return fmt.Sprintf(">%d", maxDistinct)
+	}
+	return fmt.Sprint(len(c.distinct))
+}
+
+func (c *columnStats) unique() bool {
+	return !c.overflow && c.nulls == 0 && c.values > 0 && len(c.distinct) == c.values
+}
+
+// labelRate reports whether the column looks like a binary ground-truth label
+// and, if so, the share of positive values.
+func (c *columnStats) labelRate() (float64, bool) {
+	if c.overflow || c.values == 0 || len(c.distinct) > 2 || !labelName.MatchString(c.name) {
+		return 0, false
+	}
+	pos := 0
+	for v, n := range c.distinct {
+		switch strings.ToLower(v) {
+		case "1", "true", "yes", "y", "t":
+			pos += n
+		case "0", "false", "no", "n", "f":
+		default:
+			return 0, false
+		}
+	}
+	return float64(pos) / float64(c.values), true
+}
+
+func (c *columnStats) bounds() (string, string) {
+	switch c.kind() {
+	case typeInt, typeDecimal:
+		return c.minText, c.maxText
+	case typeBool:
+		return "", ""
+	}
+	return truncate(c.minStr, 32), truncate(c.maxStr, 32)
+}
+
+func truncate(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// runInfer samples up to rows records, prints a per-column report and writes a
+// starter mapping to output.
+func runInfer(input, ext, output string, rows int) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var cols []*columnStats
+	byName := map[string]*columnStats{}
+	column := func(name string) *columnStats {
+		c, ok := byName[name]
+		if !ok {
+			c = newColumnStats(name)
+			byName[name] = c
+			cols = append(cols, c)
+		}
+		return c
+	}
+
+	sampled := 0
+	switch ext {
+	case ".csv":
+		cr := csv.NewReader(f)
+		cr.TrimLeadingSpace = true
+		headers, err := cr.Read()
+		if err != nil {
+			return fmt.Errorf("read header: %w", err)
+		}
+		for _, h := range headers {
+			column(h)
+		}
+		for rows <= 0 || sampled < rows {
+			record, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("read row %d: %w", sampled+1, err)
+			}
+			if len(record) != len(headers) {
+				return fmt.Errorf("row %d: header/data length mismatch (%d vs %d)", sampled+1, len(headers), len(record))
+			}
+			for i, h := range headers {
+				byName[h].observe(record[i])
+			}
+			sampled++
+		}
+	case ".jsonl", ".ndjson":
+		records := newJSONLRecords(f)
+		for rows <= 0 || sampled < rows {
+			rec, err := records.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			keys := make([]string, 0, len(rec))
+			for k := range rec {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				column(k)
+			}
+			// A key missing from this record counts as a null.
+			for _, c := range cols {
+				c.observe(rec[c.name])
+			}
+			sampled++
+		}
+	default:
+		return fmt.Errorf("unsupported input extension %q (expected .csv or .jsonl/.ndjson)", ext)
+	}
+	if sampled == 0 {
+		return errors.New("no rows to sample")
+	}
+
+	fmt.Printf("Sampled %d row(s) from %s\n\n", sampled, input)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLUMN\tTYPE\tNULL%\tDISTINCT\tMIN\tMAX")
+	for _, c := range cols {
+		lo, hi := c.bounds()
+		fmt.Fprintf(tw, "%s\t%s\t%.1f\t%s\t%s\t%s\n", c.name, c.kind(), 100*float64(c.nulls)/float64(sampled), c.cardinality(), lo, hi)
+	}
+	tw.Flush()
+
+	fmt.Println("\nTimestamp candidates:")
+	found := false
+	for _, c := range cols {
+		if layouts := c.timeLayouts(); len(layouts) > 0 {
+			fmt.Printf("  %s: %s\n", c.name, strings.Join(layouts, " | "))
+			found = true
+		}
+	}
+	if !found {
+		fmt.Println("  (none)")
+	}
+
+	fmt.Println("\nLabel candidates:")
+	found = false
+	for _, c := range cols {
+		if rate, ok := c.labelRate(); ok {
+			fmt.Printf("  %s: %.2f%% positive\n", c.name, 100*rate)
+			found = true
+		}
+	}
+	if !found {
+		fmt.Println("  (none)")
+	}
+
+	m := starterMapping(cols)
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("\nWrote starter mapping to %s; review it before converting.\n", output)
+	return nil
+}
+
+// starterMapping proposes a mapping from the sampled columns: the likeliest
+// timestamp and idempotency key, label columns listed for --labels and dropped
+// so the detector never sees the answer, and string and json columns pinned so
+// coerce cannot reinterpret them.
+func starterMapping(cols []*columnStats) *mapping {
+	m := &mapping{Type: "log"}
+
+	var ts *columnStats
+	for _, c := range cols {
+		if len(c.timeLayouts()) == 0 {
+			continue
+		}
+		if ts == nil || (timestampName.MatchString(c.name) && !timestampName.MatchString(ts.name)) {
+			ts = c
+		}
+	}
+	if ts != nil {
+		m.Timestamp = ts.name
+		m.TimeLayout = ts.timeLayouts()[0]
+	}
+
+	var id *columnStats
+	for _, c := range cols {
+		if c == ts || !c.unique() || !idName.MatchString(c.name) {
+			continue
+		}
+		if id == nil || (idNameStrong.MatchString(c.name) && !idNameStrong.MatchString(id.name)) {
+			id = c
+		}
+	}
+	if id != nil {
+		m.IdempotencyKey = id.name
+	}
+
+	for _, c := range cols {
+		if c == ts || c == id {
+			continue
+		}
+		if _, ok := c.labelRate(); ok {
+			m.Labels = append(m.Labels, c.name)
+			m.Drop = append(m.Drop, c.name)
+			continue
+		}
+		m.Body = append(m.Body, c.name)
+		if k := c.kind(); k == typeString || k == typeJSON {
+			if m.Types == nil {
+				m.Types = map[string]string{}
+			}
+			m.Types[c.name] = k
+		}
+	}
+	return m
+}
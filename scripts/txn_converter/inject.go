@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Anomaly kinds for --inject, applied in this order when drawing.
+const (
+	injectSpike       = "spike"        // numeric value multiplied by --spike-factor
+	injectNewCategory = "new_category" // text value replaced by one never seen
+	injectDropout     = "dropout"      // field removed
+	injectFormat      = "format"       // number rewritten as grouped text
+	injectBurst       = "burst"        // record repeated --burst-size times
+	injectEncoding    = "encoding"     // text value base64-encoded
+)
+
+var injectKinds = []string{injectSpike, injectNewCategory, injectDropout, injectFormat, injectBurst, injectEncoding}
+
+// injection says what, if anything, was injected into a record.
+type injection struct {
+	kind   string
+	column string
+}
+
+// injector perturbs a seeded fraction of records so benchmark data has
+// anomalies at known positions; the sidecar records each one. At most one
+// anomaly is injected per input record.
+type injector struct {
+	rates   map[string]float64 // per-record probability of each kind
+	columns map[string]bool    // columns that may be perturbed; nil means any
+	skip    map[string]bool    // never perturbed: timestamp, type, id, sequence and labels
+	idField string             // suffixed on burst copies so they are not deduplicated
+	factor  float64
+	burst   int
+	after   int // records left untouched first, as a clean baseline
+	rng     *rand.Rand
+
+	seen    int
+	pending []map[string]interface{}
+	cur     injection
+}
+
+// parseInject parses "spike=0.01,dropout=0.005".
+func parseInject(spec string) (map[string]float64, error) {
+	rates := map[string]float64{}
+	total := 0.0
+	for _, part := range splitList(spec) {
+		kind, r, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("--inject: %q is not kind=rate", part)
+		}
+		known := false
+		for _, k := range injectKinds {
+			known = known || k == kind
+		}
+		if !known {
+			return nil, fmt.Errorf("--inject: unknown kind %q (expected %s)", kind, strings.Join(injectKinds, ", "))
+		}
+		rate, err := strconv.ParseFloat(r, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("--inject: rate for %s must be between 0 and 1", kind)
+		}
+		rates[kind] = rate
+		total += rate
+	}
+	if total > 1 {
+		return nil, fmt.Errorf("--inject: rates add up to %g, more than 1", total)
+	}
+	return rates, nil
+}
+
+// mark reports what was injected into the record last returned by wrap.
+func (in *injector) mark() injection {
+	if in == nil {
+		return injection{}
+	}
+	return in.cur
+}
+
+// wrap returns next with anomalies injected.
+func (in *injector) wrap(next func() (map[string]interface{}, error)) func() (map[string]interface{}, error) {
+	if in == nil {
+		return next
+	}
+	return func() (map[string]interface{}, error) {
+		if len(in.pending) > 0 {
+			rec := in.pending[0]
+			in.pending = in.pending[1:]
+			in.cur = injection{kind: injectBurst}
+			return rec, nil
+		}
+		rec, err := next()
+		if err != nil {
+			return nil, err
+		}
+		in.cur = injection{}
+		in.seen++
+		if in.seen <= in.after {
+			return rec, nil
+		}
+		r := in.rng.Float64()
+		for _, kind := range injectKinds {
+			if r < in.rates[kind] {
+				in.apply(kind, rec)
+				break
+			}
+			r -= in.rates[kind]
+		}
+		return rec, nil
+	}
+}
+
+func (in *injector) apply(kind string, rec map[string]interface{}) {
+	if kind == injectBurst {
+		for i := 1; i < in.burst; i++ {
+			dup := make(map[string]interface{}, len(rec))
+			for k, v := range rec {
+				dup[k] = v
+			}
+			if v, ok := dup[in.idField]; ok && in.idField != "" {
+				dup[in.idField] = fmt.Sprintf("%s-dup%d", text(v), i)
+			}
+			in.pending = append(in.pending, dup)
+		}
+		return
+	}
+
+	col := in.pick(rec, kind)
+	if col == "" {
+		return
+	}
+	switch kind {
+	case injectSpike:
+		f, _ := strconv.ParseFloat(text(rec[col]), 64)
+		out := f * in.factor
+		if !strings.ContainsAny(text(rec[col]), ".eE") {
+			out = math.Round(out)
+		}
+		rec[col] = json.Number(strconv.FormatFloat(out, 'f', -1, 64))
+	case injectNewCategory:
+		rec[col] = fmt.Sprintf("injected_%08x", in.rng.Uint32())
+	case injectDropout:
+		delete(rec, col)
+	case injectFormat:
+		f, _ := strconv.ParseFloat(text(rec[col]), 64)
+		rec[col] = grouped(f)
+	case injectEncoding:
+		rec[col] = base64.StdEncoding.EncodeToString([]byte(rec[col].(string)))
+	}
+	in.cur = injection{kind: kind, column: col}
+}
+
+// pick chooses a column the kind can perturb, or "" if there is none.
+func (in *injector) pick(rec map[string]interface{}, kind string) string {
+	var cands []string
+	for col, v := range rec {
+		if in.skip[col] || (in.columns != nil && !in.columns[col]) {
+			continue
+		}
+		numeric := false
+		switch v := v.(type) {
+		case json.Number, int, float64:
+			numeric = true
+		case string:
+			numeric = jsonNumber.MatchString(v)
+		}
+		_, isText := v.(string)
+		switch kind {
+		case injectSpike, injectFormat:
+			if !numeric {
+				continue
+			}
+		case injectNewCategory, injectEncoding:
+			if !isText || numeric || v == "" {
+				continue
+			}
+		}
+		cands = append(cands, col)
+	}
+	if len(cands) == 0 {
+		return ""
+	}
+	sort.Strings(cands) // map order must not leak into the choice
+	return cands[in.rng.Intn(len(cands))]
+}
+
+// grouped formats f with thousands separators and four decimals, e.g.
+// 1234.5 as "1,234.5000".
+func grouped(f float64) string {
+	s := strconv.FormatFloat(math.Abs(f), 'f', 4, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if f < 0 {
+		b.WriteByte('-')
+	}
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return b.String() + "." + frac
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// labelRecord is one line of the ground-truth sidecar: the labels stripped
+// from the event with the given sequence and idempotency key, and any anomaly
+// --inject put into it. Every emitted event gets a line, so negatives are
+// explicit.
+type labelRecord struct {
+	Sequence       int64                  `json:"sequence"`
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"`
+	Labels         map[string]interface{} `json:"labels"`
+	Injected       string                 `json:"injected,omitempty"`
+	InjectedColumn string                 `json:"injected_column,omitempty"`
+}
+
+// labelWriter writes the sidecar as JSONL.
+type labelWriter struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func createLabelWriter(path string) (*labelWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &labelWriter{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (lw *labelWriter) add(seq int64, key string, labels map[string]interface{}, inj injection) error {
+	b, err := json.Marshal(labelRecord{Sequence: seq, IdempotencyKey: key, Labels: labels, Injected: inj.kind, InjectedColumn: inj.column})
+	if err != nil {
+		return fmt.Errorf("marshal labels for %d: %w", seq, err)
+	}
+	lw.w.Write(b)
+	return lw.w.WriteByte('\n')
+}
+
+func (lw *labelWriter) Close() error {
+	if err := lw.w.Flush(); err != nil {
+		lw.f.Close()
+		return err
+	}
+	return lw.f.Close()
+}
+
+// splitLabels removes the label columns from rec and returns them, so the
+// detector never sees the answer in the payload it compresses.
+func splitLabels(rec map[string]interface{}, cols []string) map[string]interface{} {
+	labels := map[string]interface{}{}
+	for _, c := range cols {
+		if v, ok := rec[c]; ok {
+			labels[c] = v
+			delete(rec, c)
+		}
+	}
+	return labels
+}
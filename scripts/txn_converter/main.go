@@ -2,188 +2,298 @@ package main
 
 import (
 	"bufio"
-	"encoding/csv"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 )
 
 // convertTransactionsToNDJSON converts CSV or JSONL transaction datasets into
-// per-event NDJSON ready to wrap into { "events": [...] } requests for Driftlock.
-// It aims to stay dependency-free (stdlib only) and supports light coercion
-// (timestamps, numbers, booleans).
+// either per-event NDJSON or ready-to-POST /v1/detect request bodies, one per
+// line. It aims to stay dependency-free (stdlib only) and supports light
+// coercion (timestamps, numbers, booleans).
+//
+//	go run ./scripts/txn_converter/*.go --input fraud.csv --output fraud.ndjson --dataset fraud
+//	go run ./scripts/txn_converter/*.go --input fraud.csv --output fraud.requests --format detect --stream-id fraud-demo
+//	while read -r body; do curl -sS -H "X-Api-Key: $KEY" -H 'Content-Type: application/json' -d "$body" https://api.driftlock.net/v1/detect; done < fraud.requests
 func main() {
 	input := flag.String("input", "", "Path to CSV or JSONL input file")
 	output := flag.String("output", "", "Path to NDJSON output file")
 	limit := flag.Int("limit", 1000, "Maximum number of records to emit")
 	tsField := flag.String("timestamp", "timestamp", "Timestamp column/field name (CSV)")
-	timeLayout := flag.String("time-layout", time.RFC3339, "Go time layout for parsing the timestamp field")
-	dataset := flag.String("dataset", "", "Optional preset for known datasets (e.g. fraud)")
+	timeLayout := flag.String("time-layout", layoutAuto, "Timestamp format: auto, a Go time layout, epoch (unit by magnitude), epoch_s|ms|us|ns, or offset_s|ms|us|ns from --epoch-base")
+	tz := flag.String("tz", "", "Time zone (IANA name) for timestamps without one (default UTC)")
+	epochBase := flag.String("epoch-base", "", "RFC 3339 instant that offset_* timestamps count from")
+	dataset := flag.String("dataset", "", "Preset for a known dataset: "+strings.Join(presetNames(), ", ")+" (see --list-datasets)")
+	listDatasets := flag.Bool("list-datasets", false, "List dataset presets and exit")
+	seed := flag.Int64("seed", 1, "Seed for preset noise and sampling; the same seed and input give the same output")
+	format := flag.String("format", "ndjson", "Output format: ndjson (one flat object per row) or detect (one /v1/detect request body per line)")
+	streamID := flag.String("stream-id", "", "detect: stream_id for every request (default: the API key's stream)")
+	eventType := flag.String("event-type", "log", "detect: event type (log, metric, trace or llm)")
+	idField := flag.String("idempotency-field", "", "detect: column to use as each event's idempotency_key")
+	batchSize := flag.Int("batch-size", maxEventsPerRequest, "detect: events per request (at most 256)")
+	maxBytes := flag.Int("max-request-bytes", maxRequestBytes, "detect: maximum encoded size of one request body")
+	types := flag.String("types", "", "Column type hints, e.g. cc_num=string,amt=decimal (string, int, decimal, bool, timestamp, json)")
+	infer := flag.Bool("infer", false, "Sample --limit rows, report on each column and write a starter mapping to --output")
+	labelsPath := flag.String("labels", "", "Strip label columns from events and write them, keyed by sequence and idempotency_key, to this JSONL sidecar")
+	labelCols := flag.String("label-columns", "", "Comma-separated label columns for --labels (default: the preset's, else label)")
+	sample := flag.String("sample", "", "Sampling: reservoir (uniform --limit rows, kept in input order) or stratified (per-value --rates on --stratify)")
+	stratify := flag.String("stratify", "label", "stratified: column whose value selects the keep rate")
+	rates := flag.String("rates", "", "stratified: value=rate pairs, * for other values, e.g. 1=1,0=0.01")
+	since := flag.String("since", "", "Keep rows at or after this RFC 3339 time")
+	until := flag.String("until", "", "Keep rows before this RFC 3339 time")
+	var where wheres
+	flag.Var(&where, "where", "Keep rows where column=value, column!=value, column~regex or column!~regex (repeatable; all must hold)")
+	inject := flag.String("inject", "", "Inject anomalies at per-row rates, e.g. spike=0.01,dropout=0.005 (spike, new_category, dropout, format, burst, encoding); needs --labels")
+	injectCols := flag.String("inject-columns", "", "Comma-separated columns --inject may perturb (default: all but timestamp, type, idempotency key, sequence and labels)")
+	injectAfter := flag.Int("inject-after", 0, "Leave this many rows untouched before injecting, as a clean baseline")
+	spikeFactor := flag.Float64("spike-factor", 10, "Multiplier for spike anomalies")
+	burstSize := flag.Int("burst-size", 5, "Copies of a record in a burst anomaly, including the original")
+	mappingPath := flag.String("mapping", "", "JSON file mapping columns to event fields; with --format ndjson, writes one event object per line")
 	flag.Parse()
 
+	if *listDatasets {
+		for _, name := range presetNames() {
+			fmt.Printf("%-11s %s\n", name, presets[name].description)
+		}
+		return
+	}
 	if *input == "" || *output == "" {
 		flag.Usage()
 		os.Exit(2)
 	}
 
 	inExt := strings.ToLower(filepath.Ext(*input))
-	switch inExt {
-	case ".csv":
-		if err := convertCSV(*input, *output, *limit, *tsField, *timeLayout, *dataset); err != nil {
+	if *infer {
+		if err := runInfer(*input, inExt, *output, *limit); err != nil {
 			fail(err)
 		}
-	case ".jsonl", ".ndjson":
-		if err := copyNDJSON(*input, *output, *limit); err != nil {
-			fail(err)
-		}
-	default:
-		fail(fmt.Errorf("unsupported input extension %q (expected .csv or .jsonl/.ndjson)", inExt))
+		return
 	}
 
-	fmt.Printf("Wrote NDJSON to %s\n", *output)
-}
-
-func convertCSV(input, output string, limit int, tsField, timeLayout, dataset string) error {
-	f, err := os.Open(input)
+	p, err := lookupPreset(*dataset)
 	if err != nil {
-		return err
+		fail(err)
 	}
-	defer f.Close()
-
-	r := csv.NewReader(f)
-	r.TrimLeadingSpace = true
-
-	headers, err := r.Read()
-	if err != nil {
-		return fmt.Errorf("read header: %w", err)
+	if p != nil {
+		// Presets read delimited text; C-MAPSS ships as .txt.
+		if inExt != ".csv" && !(p.whitespace && inExt == ".txt") {
+			fail(fmt.Errorf("--dataset %s reads CSV input, not %q", *dataset, inExt))
+		}
+		inExt = ".csv"
 	}
+	opts := csvOptions{tsField: *tsField, preset: p, rng: rand.New(rand.NewSource(*seed)), parsers: map[string]*timeParser{}}
 
-	out, err := os.Create(output)
+	// The mapping file wins, then flags given on the command line, then the
+	// preset's defaults, then flag defaults.
+	m := &mapping{}
+	if *mappingPath != "" {
+		if m, err = loadMapping(*mappingPath); err != nil {
+			fail(err)
+		}
+	}
+	if p != nil {
+		set := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if m.TimeLayout == "" && !set["time-layout"] {
+			m.TimeLayout = p.timeLayout
+		}
+		if m.EpochBase == "" && !set["epoch-base"] {
+			m.EpochBase = p.epochBase
+		}
+		if m.IdempotencyKey == "" && !set["idempotency-field"] {
+			m.IdempotencyKey = p.idField
+		}
+	}
+	if m.Timestamp == "" {
+		m.Timestamp = *tsField
+	}
+	if m.TimeLayout == "" {
+		m.TimeLayout = *timeLayout
+	}
+	if m.TimeZone == "" {
+		m.TimeZone = *tz
+	}
+	if m.EpochBase == "" {
+		m.EpochBase = *epochBase
+	}
+	if m.Type == "" && m.TypeColumn == "" {
+		m.Type = *eventType
+	}
+	if m.IdempotencyKey == "" {
+		m.IdempotencyKey = *idField
+	}
+	flagTypes, err := parseTypes(*types)
 	if err != nil {
-		return err
+		fail(err)
 	}
-	defer out.Close()
-
-	w := bufio.NewWriter(out)
-	defer w.Flush()
-
-	row := 0
-	for {
-		if limit > 0 && row >= limit {
-			break
+	for col, t := range flagTypes {
+		if m.Types == nil {
+			m.Types = map[string]string{}
+		}
+		m.Types[col] = t
+	}
+	if cols := splitList(*labelCols); len(cols) > 0 {
+		m.Labels = cols
+	}
+	if len(m.Labels) == 0 {
+		m.Labels = []string{"label"}
+		if p != nil && p.labels != nil {
+			m.Labels = p.labels
 		}
+	}
+	if err := m.validate(); err != nil {
+		fail(err)
+	}
+	if opts.times, err = newTimeConfig(m.TimeLayout, m.TimeZone, m.EpochBase); err != nil {
+		fail(err)
+	}
+	opts.tsField, opts.types, opts.labelCols = m.Timestamp, m.Types, m.Labels
 
-		record, err := r.Read()
-		if errors.Is(err, io.EOF) {
-			break
+	sel := &selection{where: where, mode: *sample, size: *limit, column: *stratify, rng: rand.New(rand.NewSource(*seed))}
+	for _, b := range []struct {
+		flag string
+		val  string
+		dst  *time.Time
+	}{{"since", *since, &sel.since}, {"until", *until, &sel.until}} {
+		if b.val == "" {
+			continue
 		}
-		if err != nil {
-			return fmt.Errorf("read row %d: %w", row+1, err)
+		if *b.dst, err = time.Parse(time.RFC3339Nano, b.val); err != nil {
+			fail(fmt.Errorf("--%s: %w", b.flag, err))
 		}
-		if len(record) != len(headers) {
-			return fmt.Errorf("row %d: header/data length mismatch (%d vs %d)", row+1, len(headers), len(record))
+	}
+	switch *sample {
+	case "":
+	case sampleReservoir:
+		if *limit <= 0 {
+			fail(errors.New("--sample reservoir needs a positive --limit"))
 		}
-
-		event := map[string]interface{}{}
-		for i, h := range headers {
-			event[h] = coerce(record[i])
+	case sampleStratified:
+		if *rates == "" {
+			fail(errors.New("--sample stratified needs --rates, e.g. 1=1,0=0.01"))
 		}
-
-		if preset := strings.ToLower(dataset); preset == "fraud" {
-			if v, ok := event["is_fraud"].(float64); ok {
-				event["label"] = v == 1
-			}
+		if sel.rates, sel.defaultRate, err = parseRates(*rates); err != nil {
+			fail(err)
 		}
+	default:
+		fail(fmt.Errorf("unknown --sample %q (expected reservoir or stratified)", *sample))
+	}
+	opts.sel = sel
 
-		// Normalize timestamp
-		if raw, ok := event[tsField]; ok {
-			switch val := raw.(type) {
-			case string:
-				if ts, err := time.Parse(timeLayout, val); err == nil {
-					event["timestamp"] = ts.UTC().Format(time.RFC3339Nano)
-				}
-			case float64:
-				// Treat numeric timestamps as seconds offset from now.
-				base := time.Now().Add(-time.Duration(val) * time.Second)
-				event["timestamp"] = base.UTC().Format(time.RFC3339Nano)
-			}
+	if *inject != "" {
+		if *labelsPath == "" {
+			fail(errors.New("--inject needs --labels so the injected positions are recorded"))
 		}
-
-		b, err := json.Marshal(event)
-		if err != nil {
-			return fmt.Errorf("marshal row %d: %w", row+1, err)
+		if *burstSize < 2 {
+			fail(errors.New("--burst-size must be at least 2"))
+		}
+		in := &injector{
+			skip: map[string]bool{
+				"timestamp": true, m.Timestamp: true, m.IdempotencyKey: true,
+				m.TypeColumn: true, m.Sequence: true,
+			},
+			idField: m.IdempotencyKey,
+			factor:  *spikeFactor,
+			burst:   *burstSize,
+			after:   *injectAfter,
+			// Its own stream, so turning injection on does not move the sample.
+			rng: rand.New(rand.NewSource(*seed + 1)),
+		}
+		if in.rates, err = parseInject(*inject); err != nil {
+			fail(err)
 		}
-		if _, err := w.Write(b); err != nil {
-			return err
+		if in.rates[injectBurst] > 0 && m.Sequence != "" && m.IdempotencyKey == "" {
+			// Copies would share the row's sequence and nothing else sets
+			// them apart, in the events or in the sidecar.
+			fail(fmt.Errorf("--inject burst with sequence column %q needs an idempotency_key so the copies stay distinct", m.Sequence))
 		}
-		if err := w.WriteByte('\n'); err != nil {
-			return err
+		for _, c := range m.Labels {
+			in.skip[c] = true
 		}
-		row++
+		if cols := splitList(*injectCols); len(cols) > 0 {
+			in.columns = map[string]bool{}
+			for _, c := range cols {
+				in.columns[c] = true
+			}
+		}
+		opts.inj = in
 	}
 
-	return nil
-}
-
-func copyNDJSON(input, output string, limit int) error {
-	in, err := os.Open(input)
-	if err != nil {
-		return err
+	if (*labelsPath != "" || sel.active() || *inject != "") && *format == "ndjson" && *mappingPath == "" && inExt != ".csv" {
+		fail(fmt.Errorf("--labels, --inject, --sample, --where, --since and --until with %s input need --mapping or --format detect", inExt))
 	}
-	defer in.Close()
-
-	out, err := os.Create(output)
-	if err != nil {
-		return err
+	if *labelsPath != "" {
+		if opts.sidecar, err = createLabelWriter(*labelsPath); err != nil {
+			fail(err)
+		}
+		defer func() {
+			if err := opts.sidecar.Close(); err != nil {
+				fail(err)
+			}
+			fmt.Printf("Wrote labels to %s\n", *labelsPath)
+		}()
 	}
-	defer out.Close()
-
-	sc := bufio.NewScanner(in)
-	w := bufio.NewWriter(out)
-	defer w.Flush()
 
-	line := 0
-	for sc.Scan() {
-		if limit > 0 && line >= limit {
-			break
+	switch {
+	case *format == "ndjson" && *mappingPath != "":
+		err := convertEvents(*input, inExt, *output, *limit, opts, m, func(w io.Writer) (eventSink, error) {
+			return &eventLineWriter{w: bufio.NewWriter(w)}, nil
+		})
+		if err != nil {
+			fail(err)
 		}
-		if _, err := w.Write(sc.Bytes()); err != nil {
-			return err
+	case *format == "ndjson":
+		switch inExt {
+		case ".csv":
+			if err := convertCSV(*input, *output, *limit, opts); err != nil {
+				fail(err)
+			}
+		case ".jsonl", ".ndjson":
+			if err := copyNDJSON(*input, *output, *limit); err != nil {
+				fail(err)
+			}
+		default:
+			fail(fmt.Errorf("unsupported input extension %q (expected .csv or .jsonl/.ndjson)", inExt))
+		}
+	case *format == "detect":
+		if *batchSize < 1 || *batchSize > maxEventsPerRequest {
+			fail(fmt.Errorf("--batch-size must be between 1 and %d", maxEventsPerRequest))
 		}
-		if err := w.WriteByte('\n'); err != nil {
-			return err
+		if *maxBytes < 1 || *maxBytes > maxRequestBytes {
+			fail(fmt.Errorf("--max-request-bytes must be between 1 and %d", maxRequestBytes))
+		}
+		var rw *requestWriter
+		err := convertEvents(*input, inExt, *output, *limit, opts, m, func(w io.Writer) (eventSink, error) {
+			var err error
+			rw, err = newRequestWriter(w, batchOptions{streamID: *streamID, batchSize: *batchSize, maxBytes: *maxBytes})
+			return rw, err
+		})
+		if err != nil {
+			fail(err)
 		}
-		line++
+		fmt.Printf("Wrote %d request(s) to %s\n", rw.requests, *output)
+		return
+	default:
+		fail(fmt.Errorf("unknown --format %q (expected ndjson or detect)", *format))
 	}
-	return sc.Err()
+
+	fmt.Printf("Wrote NDJSON to %s\n", *output)
 }
 
-func coerce(val string) interface{} {
-	if val == "" {
-		return val
-	}
-	if i, err := strconv.ParseInt(val, 10, 64); err == nil {
-		return i
-	}
-	if f, err := strconv.ParseFloat(val, 64); err == nil {
-		return f
-	}
-	lower := strings.ToLower(val)
-	if lower == "true" || lower == "false" {
-		return lower == "true"
-	}
-	// Unquote if it looks like a quoted string with doubled quotes (CSV)
-	if strings.HasPrefix(val, "\"") && strings.HasSuffix(val, "\"") {
-		return strings.Trim(val, "\"")
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
 	}
-	return val
+	return out
 }
 
 func fail(err error) {
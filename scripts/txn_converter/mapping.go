@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mapping describes how input columns become /v1/detect events, so a new
+// customer dataset can be onboarded with a JSON file instead of code. Column
+// names always refer to the input; rename only changes the output key.
+//
+//	{
+//	  "timestamp": "trans_date_trans_time",
+//	  "time_layout": "2006-01-02 15:04:05",
+//	  "type": "log",
+//	  "idempotency_key": "trans_num",
+//	  "body": ["amt", "merchant", "category"],
+//	  "attributes": ["state"],
+//	  "rename": {"amt": "amount_usd"},
+//	  "drop": ["cc_num"],
+//	  "constants": {"attributes": {"dataset": "kaggle-fraud"}},
+//	  "types": {"cc_num": "string", "amt": "decimal"},
+//	  "labels": ["is_fraud"]
+//	}
+//
+// types pins a column to string, int, decimal, bool, timestamp or json instead
+// of letting coerce guess. labels are the ground-truth columns that --labels
+// moves to the sidecar. When body is empty every column not otherwise claimed (timestamp, type,
+// idempotency key, sequence, attributes, drop) goes into the body.
+type mapping struct {
+	Timestamp      string            `json:"timestamp,omitempty"`
+	TimeLayout     string            `json:"time_layout,omitempty"`
+	TimeZone       string            `json:"time_zone,omitempty"`
+	EpochBase      string            `json:"epoch_base,omitempty"`
+	Type           string            `json:"type,omitempty"`
+	TypeColumn     string            `json:"type_column,omitempty"`
+	IdempotencyKey string            `json:"idempotency_key,omitempty"`
+	Sequence       string            `json:"sequence,omitempty"`
+	Body           []string          `json:"body,omitempty"`
+	Attributes     []string          `json:"attributes,omitempty"`
+	Rename         map[string]string `json:"rename,omitempty"`
+	Drop           []string          `json:"drop,omitempty"`
+	Constants      *constants        `json:"constants,omitempty"`
+	Types          map[string]string `json:"types,omitempty"`
+	Labels         []string          `json:"labels,omitempty"`
+
+	path string // file the mapping was loaded from, if any
+}
+
+// constants are fixed fields added to every event.
+type constants struct {
+	Body       map[string]interface{} `json:"body,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+var eventTypes = map[string]bool{"log": true, "metric": true, "trace": true, "llm": true}
+
+// loadMapping reads a mapping file, rejecting unknown keys so a typo does not
+// silently route a column into the body.
+func loadMapping(path string) (*mapping, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m mapping
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("mapping %s: %w", path, err)
+	}
+	m.path = path
+	return &m, nil
+}
+
+// validate checks the mapping is self-consistent.
+func (m *mapping) validate() error {
+	if m.Type != "" && !eventTypes[m.Type] {
+		return fmt.Errorf("mapping: type must be log, metric, trace or llm, got %q", m.Type)
+	}
+	if err := checkTypes(m.Types); err != nil {
+		return fmt.Errorf("mapping: %w", err)
+	}
+	if m.Type != "" && m.TypeColumn != "" {
+		return fmt.Errorf("mapping: set type or type_column, not both")
+	}
+	seen := map[string]string{}
+	claim := func(role string, cols ...string) error {
+		for _, c := range cols {
+			if c == "" {
+				continue
+			}
+			if prev, ok := seen[c]; ok {
+				return fmt.Errorf("mapping: column %q is both %s and %s", c, prev, role)
+			}
+			seen[c] = role
+		}
+		return nil
+	}
+	if err := claim("body", m.Body...); err != nil {
+		return err
+	}
+	if err := claim("attributes", m.Attributes...); err != nil {
+		return err
+	}
+	if err := claim("drop", m.Drop...); err != nil {
+		return err
+	}
+	out := map[string]string{}
+	for src, dst := range m.Rename {
+		if dst == "" {
+			return fmt.Errorf("mapping: rename of %q is empty", src)
+		}
+		if prev, ok := out[dst]; ok {
+			return fmt.Errorf("mapping: %q and %q are both renamed to %q", prev, src, dst)
+		}
+		out[dst] = src
+	}
+	return nil
+}
+
+// checkColumns reports columns named in a mapping file but missing from the
+// columns records carry once any preset has run (csvRecords.fields).
+// Dropping an absent column is harmless, and flag defaults are not checked; a
+// CSV without a timestamp column is fine.
+func (m *mapping) checkColumns(headers []string) error {
+	if m.path == "" {
+		return nil
+	}
+	have := map[string]bool{}
+	for _, h := range headers {
+		have[h] = true
+	}
+	var missing []string
+	drop := map[string]bool{}
+	for _, c := range m.Drop {
+		drop[c] = true
+	}
+	cols := m.columns()
+	for c := range m.Types {
+		cols = append(cols, c)
+	}
+	for _, c := range cols {
+		if !have[c] && !drop[c] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("mapping %s names columns not in the header: %s", m.path, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (m *mapping) columns() []string {
+	cols := append(append(append([]string{}, m.Body...), m.Attributes...), m.Drop...)
+	for _, c := range []string{m.Timestamp, m.TypeColumn, m.IdempotencyKey, m.Sequence} {
+		if c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+func (m *mapping) name(col string) string {
+	if n, ok := m.Rename[col]; ok {
+		return n
+	}
+	return col
+}
+
+// toEvent builds the event for the seq'th record (1-based). seq is the
+// sequence unless the mapping names a sequence column.
+func (m *mapping) toEvent(rec map[string]interface{}, seq int64) (detectEvent, error) {
+	ev := detectEvent{Type: m.Type, Body: map[string]interface{}{}, Sequence: seq}
+	if ts, ok := rec["timestamp"].(string); ok {
+		ev.Timestamp = ts
+	}
+
+	if m.TypeColumn != "" {
+		v, ok := rec[m.TypeColumn]
+		if !ok || v == nil || v == "" {
+			return ev, fmt.Errorf("record %d: type column %q is empty", seq, m.TypeColumn)
+		}
+		t := strings.ToLower(fmt.Sprint(v))
+		if !eventTypes[t] {
+			return ev, fmt.Errorf("record %d: type column %q has %q, not log, metric, trace or llm", seq, m.TypeColumn, fmt.Sprint(v))
+		}
+		ev.Type = t
+	}
+	if m.IdempotencyKey != "" {
+		v, ok := rec[m.IdempotencyKey]
+		if !ok || v == nil || v == "" {
+			return ev, fmt.Errorf("record %d: idempotency field %q is empty", seq, m.IdempotencyKey)
+		}
+		ev.IdempotencyKey = fmt.Sprint(v)
+	}
+	if m.Sequence != "" {
+		n, err := strconv.ParseInt(fmt.Sprint(rec[m.Sequence]), 10, 64)
+		if err != nil {
+			return ev, fmt.Errorf("record %d: sequence field %q is not an integer: %v", seq, m.Sequence, rec[m.Sequence])
+		}
+		ev.Sequence = n
+	}
+
+	for _, c := range m.Attributes {
+		if v, ok := rec[c]; ok {
+			if ev.Attributes == nil {
+				ev.Attributes = map[string]interface{}{}
+			}
+			ev.Attributes[m.name(c)] = v
+		}
+	}
+	if len(m.Body) > 0 {
+		for _, c := range m.Body {
+			if v, ok := rec[c]; ok {
+				ev.Body[m.name(c)] = v
+			}
+		}
+	} else {
+		claimed := map[string]bool{"timestamp": true}
+		for _, c := range m.columns() {
+			claimed[c] = true
+		}
+		for k, v := range rec {
+			if !claimed[k] {
+				ev.Body[m.name(k)] = v
+			}
+		}
+	}
+
+	if m.Constants == nil {
+		return ev, nil
+	}
+	for k, v := range m.Constants.Body {
+		ev.Body[k] = v
+	}
+	for k, v := range m.Constants.Attributes {
+		if ev.Attributes == nil {
+			ev.Attributes = map[string]interface{}{}
+		}
+		ev.Attributes[k] = v
+	}
+	return ev, nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// preset knows how to read one benchmark dataset. The ported presets are Go
+// versions of scripts/transform_*.py and emit the same driftlock_ready
+// schema (timestamp, transaction_id, amount_usd, processing_ms,
+// origin_country, api_endpoint, status), plus label where the dataset has
+// ground truth. Random noise in the Python scripts comes from --seed here, so
+// reruns are byte-identical.
+type preset struct {
+	description string
+	columns     []string // header for headerless input
+	whitespace  bool     // fields separated by runs of whitespace, no quoting
+	fields      []string // columns transform emits; nil if it keeps the input's
+	adds        []string // columns transform adds to the input's, when fields is nil
+	timeLayout  string   // default --time-layout for the timestamp field
+	epochBase   string   // default --epoch-base for offset layouts
+	idField     string   // default idempotency key
+	labels      []string // ground-truth columns for --labels
+	sorted      bool     // emit rows in timestamp order, not input order
+	tail        int      // keep only the last tail rows, after sorting; 0 keeps all
+	transform   func(r presetRow) (map[string]interface{}, error)
+}
+
+// presetRow is one coerced input row. index counts data rows from 0, like
+// the pandas index the Python scripts used. A nil transform result skips the
+// row.
+type presetRow struct {
+	rec   map[string]interface{}
+	index int
+	rng   *rand.Rand
+	times *timeParser // for timestamps the transform itself must read
+}
+
+// readySchema is the driftlock_ready output of the ported presets.
+var readySchema = []string{"timestamp", "transaction_id", "amount_usd", "processing_ms", "origin_country", "api_endpoint", "status", "label"}
+
+// readyLabels are the ground truth in readySchema; the Python scripts put the
+// label in status.
+var readyLabels = []string{"label", "status"}
+
+// syntheticBase anchors datasets that only have a row or cycle counter; one
+// step is one second.
+const syntheticBase = "2024-01-01T00:00:00Z"
+
+var presets = map[string]*preset{
+	"fraud": {
+		description: "Raw fraud CSV rows with label set from is_fraud",
+		labels:      []string{"label", "is_fraud"},
+		adds:        []string{"label"},
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			if v, ok := r.rec["is_fraud"]; ok {
+				r.rec["label"] = fmt.Sprint(v) == "1" || v == true
+			}
+			return r.rec, nil
+		},
+	},
+	"fraud_data": {
+		description: "Kaggle credit card fraud (fraud_data.csv), as transform_fraud_data.py",
+		fields:      readySchema,
+		timeLayout:  "2006-01-02 15:04:05",
+		idField:     "transaction_id",
+		labels:      readyLabels,
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			amt := r.num("amt")
+			fraud := r.num("is_fraud") == 1
+			return map[string]interface{}{
+				"timestamp":      r.str("trans_date_trans_time"),
+				"transaction_id": r.str("trans_num"),
+				"amount_usd":     amt,
+				"processing_ms":  int(20 + amt/100 + r.rng.Float64()*50),
+				"origin_country": r.str("city") + ", " + r.str("state"),
+				"api_endpoint":   strings.TrimSpace(strings.ReplaceAll(r.str("merchant"), `"`, "")),
+				"status":         pick(fraud, "review_flagged", "approved"),
+				"label":          fraud,
+			}, nil
+		},
+	},
+	"airline": {
+		description: "BTS Airline_Delay_Cause.csv, as transform_airline.py; monthly rows, no ground truth",
+		fields:      readySchema,
+		timeLayout:  "2006-01",
+		idField:     "transaction_id",
+		labels:      readyLabels,
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			delay := r.num("arr_delay")
+			if delay < 0 {
+				delay = 0
+			}
+			return map[string]interface{}{
+				"timestamp":      fmt.Sprintf("%d-%02d", int(r.num("year")), int(r.num("month"))),
+				"transaction_id": fmt.Sprintf("flt_%s_%s_%d", r.str("carrier"), r.str("airport"), r.index),
+				"amount_usd":     r.num("arr_flights"),
+				"processing_ms":  int(delay),
+				"origin_country": r.strOr("airport_name", "Unknown"),
+				"api_endpoint":   r.strOr("carrier_name", "Unknown"),
+				"status":         pick(delay > 15, "delayed", "on_time"),
+			}, nil
+		},
+	},
+	"nasa": {
+		description: "NASA C-MAPSS train_FD001.txt (whitespace, no header), unit 1 only, as transform_nasa.py; one second per cycle",
+		columns:     append([]string{"unit", "time", "os1", "os2", "os3"}, sensorColumns(21)...),
+		whitespace:  true,
+		fields:      readySchema,
+		timeLayout:  "offset_s",
+		epochBase:   syntheticBase,
+		idField:     "transaction_id",
+		labels:      readyLabels,
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			if r.num("unit") != 1 {
+				return nil, nil
+			}
+			cycle := int(r.num("time"))
+			return map[string]interface{}{
+				"timestamp":      cycle,
+				"transaction_id": fmt.Sprintf("u1_c%d", cycle),
+				"amount_usd":     r.num("s4"),
+				"processing_ms":  int(r.num("s9")),
+				"origin_country": fmt.Sprintf("Engine_%d", int(r.num("unit"))),
+				"api_endpoint":   "Sensor_Telemetry",
+				"status":         "nominal",
+			}, nil
+		},
+	},
+	"network": {
+		description: "UNSW-NB15 testing set, CSV release, as transform_network.py; one second per row",
+		fields:      readySchema,
+		timeLayout:  "offset_s",
+		epochBase:   syntheticBase,
+		idField:     "transaction_id",
+		labels:      readyLabels,
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			ms := 1
+			if dur := r.num("dur"); dur > 0 {
+				ms = int(dur * 1000)
+			}
+			attack := r.num("label") == 1
+			return map[string]interface{}{
+				"timestamp":      r.index,
+				"transaction_id": fmt.Sprintf("net_%d", r.index),
+				"amount_usd":     r.num("sbytes") + r.num("dbytes"),
+				"processing_ms":  ms,
+				"origin_country": r.str("proto"),
+				"api_endpoint":   r.str("service"),
+				"status":         pick(attack, "attack", "normal"),
+				"label":          attack,
+			}, nil
+		},
+	},
+	"safety": {
+		description: "Prompt-injection malignant.csv, as transform_safety.py; every row is malignant; one second per row",
+		fields:      readySchema,
+		timeLayout:  "offset_s",
+		epochBase:   syntheticBase,
+		idField:     "transaction_id",
+		labels:      readyLabels,
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			prompt := r.str("text")
+			if len(prompt) > 500 {
+				prompt = prompt[:500] + "..."
+			}
+			return map[string]interface{}{
+				"timestamp":      r.index,
+				"transaction_id": fmt.Sprintf("llm_%d", r.index),
+				"amount_usd":     0.0,
+				"processing_ms":  len(prompt) / 2,
+				"origin_country": "User_Input",
+				"api_endpoint":   prompt,
+				"status":         "flagged",
+				"label":          true,
+			}, nil
+		},
+	},
+	"supply": {
+		description: "Dynamic supply chain logistics CSV, as transform_supply.py; label is the risk class; one second per row",
+		fields:      readySchema,
+		timeLayout:  "offset_s",
+		epochBase:   syntheticBase,
+		idField:     "transaction_id",
+		labels:      readyLabels,
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			ms := 100
+			if _, ok := r.rec["delivery_time_deviation"]; ok {
+				ms = int(r.num("delivery_time_deviation") * 100)
+			}
+			risk := r.str("risk_classification")
+			return map[string]interface{}{
+				"timestamp":      r.index,
+				"transaction_id": fmt.Sprintf("ship_%s_%d", r.str("product_id"), r.index),
+				"amount_usd":     r.num("shipping_costs"),
+				"processing_ms":  ms,
+				"origin_country": r.str("supplier_country"),
+				"api_endpoint":   "Route_Risk_" + risk,
+				"status":         risk,
+				"label":          risk,
+			}, nil
+		},
+	},
+	"terra": {
+		description: "Terra/LUNA price series (terra-luna.csv), as transform_terra.py: sorted by time, last 5000 rows; label marks the 9-12 May 2022 crash",
+		fields:      readySchema,
+		timeLayout:  time.RFC3339,
+		idField:     "transaction_id",
+		labels:      readyLabels,
+		sorted:      true,
+		tail:        5000,
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			ts, err := r.times.parse(r.rec["timestamp"])
+			if err != nil {
+				return nil, fmt.Errorf("timestamp: %w", err)
+			}
+			return map[string]interface{}{
+				"timestamp":      ts.Format(time.RFC3339Nano),
+				"transaction_id": fmt.Sprintf("tx_%d", ts.Unix()),
+				"amount_usd":     r.num("price"),
+				"processing_ms":  int(20 + r.rng.Float64()*50),
+				"origin_country": "Blockchain",
+				"api_endpoint":   "LUNA-USD",
+				"status":         "processed",
+				"label":          !ts.Before(terraCrashStart) && ts.Before(terraCrashEnd),
+			}, nil
+		},
+	},
+	"web": {
+		description: "NAB realAWSCloudwatch CPU series, as transform_web.py; NAB labels live outside the CSV",
+		fields:      readySchema,
+		timeLayout:  "2006-01-02 15:04:05",
+		idField:     "transaction_id",
+		labels:      readyLabels,
+		transform: func(r presetRow) (map[string]interface{}, error) {
+			val := r.num("value")
+			return map[string]interface{}{
+				"timestamp":      r.str("timestamp"),
+				"transaction_id": fmt.Sprintf("cpu_%d", r.index),
+				"amount_usd":     val,
+				"processing_ms":  int(20 + val*2),
+				"origin_country": "us-east-1",
+				"api_endpoint":   "i-5f5533",
+				"status":         pick(val > 60, "high_load", "nominal"),
+			}, nil
+		},
+	},
+}
+
+// The LUNA collapse, as used by the benchmark write-up.
+var (
+	terraCrashStart = time.Date(2022, 5, 9, 0, 0, 0, 0, time.UTC)
+	terraCrashEnd   = time.Date(2022, 5, 13, 0, 0, 0, 0, time.UTC)
+)
+
+func lookupPreset(name string) (*preset, error) {
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := presets[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown --dataset %q (known: %s)", name, strings.Join(presetNames(), ", "))
+	}
+	return p, nil
+}
+
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sensorColumns(n int) []string {
+	cols := make([]string, n)
+	for i := range cols {
+		cols[i] = fmt.Sprintf("s%d", i+1)
+	}
+	return cols
+}
+
+func pick(cond bool, yes, no string) string {
+	if cond {
+		return yes
+	}
+	return no
+}
+
+// num reads a numeric column; missing or non-numeric values read as 0, as
+// the Python scripts' NaN handling did.
+func (r presetRow) num(col string) float64 {
+	switch v := r.rec[col].(type) {
+	case json.Number:
+		return finite(v.String())
+	case string:
+		return finite(strings.TrimSpace(v))
+	case bool:
+		if v {
+			return 1
+		}
+	}
+	return 0
+}
+
+func finite(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return f
+}
+
+func (r presetRow) str(col string) string {
+	switch v := r.rec[col].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// strOr reads a text column, falling back when it is empty or numeric.
+func (r presetRow) strOr(col, fallback string) string {
+	if s, ok := r.rec[col].(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sampling modes for --sample.
+const (
+	sampleReservoir  = "reservoir"  // uniform sample of --limit rows
+	sampleStratified = "stratified" // per-value keep rates on one column
+)
+
+// predicate is one --where condition: col=value, col!=value, col~regex or
+// col!~regex. Values are compared as the text they were read from.
+type predicate struct {
+	col    string
+	negate bool
+	value  string
+	re     *regexp.Regexp
+}
+
+func parsePredicate(s string) (predicate, error) {
+	i := strings.IndexAny(s, "=~")
+	if i <= 0 {
+		return predicate{}, fmt.Errorf("--where %q: expected column=value, column!=value, column~regex or column!~regex", s)
+	}
+	p := predicate{col: s[:i], value: s[i+1:]}
+	if strings.HasSuffix(p.col, "!") {
+		p.col, p.negate = strings.TrimSuffix(p.col, "!"), true
+	}
+	if p.col == "" {
+		return predicate{}, fmt.Errorf("--where %q: missing column", s)
+	}
+	if s[i] == '~' {
+		re, err := regexp.Compile(p.value)
+		if err != nil {
+			return predicate{}, fmt.Errorf("--where %q: %w", s, err)
+		}
+		p.re = re
+	}
+	return p, nil
+}
+
+func (p predicate) match(rec map[string]interface{}) bool {
+	v := text(rec[p.col])
+	ok := v == p.value
+	if p.re != nil {
+		ok = p.re.MatchString(v)
+	}
+	return ok != p.negate
+}
+
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// wheres collects repeated --where flags.
+type wheres []predicate
+
+func (w *wheres) String() string { return "" }
+
+func (w *wheres) Set(s string) error {
+	p, err := parsePredicate(s)
+	if err != nil {
+		return err
+	}
+	*w = append(*w, p)
+	return nil
+}
+
+// selection filters and samples the record stream before it is mapped, so
+// predicates and strata can use label columns the sidecar later strips.
+type selection struct {
+	where        []predicate
+	since, until time.Time // on the normalised timestamp; zero means open
+
+	mode        string
+	size        int                // reservoir size
+	column      string             // stratified: column whose value picks the rate
+	rates       map[string]float64 // stratified: keep probability per value
+	defaultRate float64            // stratified: for values not in rates
+	rng         *rand.Rand
+}
+
+func (s *selection) active() bool {
+	return s != nil && (len(s.where) > 0 || !s.since.IsZero() || !s.until.IsZero() || s.mode != "")
+}
+
+// parseRates parses "1=1,0=0.01,*=0.1"; * sets the rate for other values,
+// which defaults to 1.
+func parseRates(spec string) (map[string]float64, float64, error) {
+	rates, def := map[string]float64{}, 1.0
+	for _, part := range splitList(spec) {
+		val, r, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, 0, fmt.Errorf("--rates: %q is not value=rate", part)
+		}
+		rate, err := strconv.ParseFloat(r, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, 0, fmt.Errorf("--rates: rate for %q must be between 0 and 1", val)
+		}
+		if val == "*" {
+			def = rate
+		} else {
+			rates[val] = rate
+		}
+	}
+	return rates, def, nil
+}
+
+// keep applies the predicates and time range.
+func (s *selection) keep(rec map[string]interface{}) (bool, error) {
+	for _, p := range s.where {
+		if !p.match(rec) {
+			return false, nil
+		}
+	}
+	if s.since.IsZero() && s.until.IsZero() {
+		return true, nil
+	}
+	raw, ok := rec["timestamp"].(string)
+	if !ok {
+		return false, nil
+	}
+	ts, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return false, fmt.Errorf("timestamp %q: %w", raw, err)
+	}
+	return !ts.Before(s.since) && (s.until.IsZero() || ts.Before(s.until)), nil
+}
+
+// wrap returns next with the selection applied. Stratified sampling streams;
+// reservoir sampling reads the whole input first, then replays the sample in
+// input order so event order is preserved.
+func (s *selection) wrap(next func() (map[string]interface{}, error)) func() (map[string]interface{}, error) {
+	if !s.active() {
+		return next
+	}
+	filtered := func() (map[string]interface{}, error) {
+		for {
+			rec, err := next()
+			if err != nil {
+				return nil, err
+			}
+			ok, err := s.keep(rec)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if s.mode == sampleStratified {
+				rate, found := s.rates[text(rec[s.column])]
+				if !found {
+					rate = s.defaultRate
+				}
+				if s.rng.Float64() >= rate {
+					continue
+				}
+			}
+			return rec, nil
+		}
+	}
+	if s.mode != sampleReservoir {
+		return filtered
+	}
+
+	var sample []map[string]interface{}
+	var err error
+	return func() (map[string]interface{}, error) {
+		if sample == nil && err == nil {
+			sample, err = s.reservoir(filtered)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(sample) == 0 {
+			return nil, io.EOF
+		}
+		rec := sample[0]
+		sample = sample[1:]
+		return rec, nil
+	}
+}
+
+// reservoir draws a uniform sample of s.size records (Algorithm R) and returns
+// it in input order.
+func (s *selection) reservoir(next func() (map[string]interface{}, error)) ([]map[string]interface{}, error) {
+	type indexed struct {
+		i   int
+		rec map[string]interface{}
+	}
+	var res []indexed
+	for i := 0; ; i++ {
+		rec, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(res) < s.size {
+			res = append(res, indexed{i, rec})
+		} else if j := s.rng.Intn(i + 1); j < s.size {
+			res[j] = indexed{i, rec}
+		}
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].i < res[b].i })
+	out := make([]map[string]interface{}, len(res))
+	for i, r := range res {
+		out[i] = r.rec
+	}
+	return out, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Special --time-layout values. Anything else is a Go time layout.
+const (
+	layoutAuto  = "auto"  // first of commonLayouts, or epoch, that fits
+	layoutEpoch = "epoch" // Unix time, unit chosen by magnitude
+)
+
+// epochUnits are the Unix-time units accepted as epoch_<unit> and
+// offset_<unit>.
+var epochUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// commonLayouts are tried, in order, by auto detection and --infer.
+var commonLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006",
+	"02/01/2006 15:04:05",
+	"02/01/2006",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.ANSIC,
+}
+
+// timeConfig is how timestamps are read, shared by every timestamp column.
+type timeConfig struct {
+	layout string
+	loc    *time.Location // zone for timestamps without one
+	base   time.Time      // anchor for offset_<unit>
+}
+
+func newTimeConfig(layout, tz, epochBase string) (timeConfig, error) {
+	cfg := timeConfig{layout: layout, loc: time.UTC}
+	if layout == "" {
+		cfg.layout = layoutAuto
+	}
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return cfg, fmt.Errorf("time zone: %w", err)
+		}
+		cfg.loc = loc
+	}
+	if epochBase != "" {
+		base, err := time.Parse(time.RFC3339Nano, epochBase)
+		if err != nil {
+			return cfg, fmt.Errorf("epoch base must be RFC 3339: %w", err)
+		}
+		cfg.base = base
+	}
+	if unit, ok := strings.CutPrefix(cfg.layout, "offset_"); ok {
+		if _, ok := epochUnits[unit]; !ok {
+			return cfg, fmt.Errorf("unknown offset unit %q (expected s, ms, us or ns)", unit)
+		}
+		if cfg.base.IsZero() {
+			return cfg, errors.New("offset timestamps need an epoch base, e.g. --epoch-base 2024-01-01T00:00:00Z")
+		}
+	}
+	if unit, ok := strings.CutPrefix(cfg.layout, "epoch_"); ok {
+		if _, ok := epochUnits[unit]; !ok {
+			return cfg, fmt.Errorf("unknown epoch unit %q (expected s, ms, us or ns)", unit)
+		}
+	}
+	return cfg, nil
+}
+
+// timeParser parses one column. auto and epoch resolve to a concrete layout
+// or unit on the first value and keep it, so an ambiguous 01/02/2024 cannot
+// be read one way on row 1 and the other way on row 900.
+type timeParser struct {
+	cfg      timeConfig
+	resolved string
+}
+
+func (c timeConfig) parser() *timeParser {
+	return &timeParser{cfg: c}
+}
+
+// parse reads v, raw text or a decoded JSON value, and returns it in UTC.
+func (p *timeParser) parse(v interface{}) (time.Time, error) {
+	var s string
+	numeric := false
+	switch v := v.(type) {
+	case string:
+		s = strings.TrimSpace(v)
+		numeric = jsonNumber.MatchString(s)
+	case json.Number:
+		s, numeric = v.String(), true
+	case int:
+		s, numeric = strconv.Itoa(v), true
+	case float64:
+		s, numeric = strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return time.Time{}, fmt.Errorf("cannot read %T as a timestamp", v)
+	}
+
+	if p.resolved == "" {
+		r, err := p.resolve(s, numeric)
+		if err != nil {
+			return time.Time{}, err
+		}
+		p.resolved = r
+	}
+
+	if unit, ok := strings.CutPrefix(p.resolved, "epoch_"); ok {
+		return fromUnits(s, epochUnits[unit], time.Unix(0, 0))
+	}
+	if unit, ok := strings.CutPrefix(p.resolved, "offset_"); ok {
+		return fromUnits(s, epochUnits[unit], p.cfg.base)
+	}
+	ts, err := time.ParseInLocation(p.resolved, s, p.cfg.loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q does not match time layout %q", s, p.resolved)
+	}
+	return ts.UTC(), nil
+}
+
+// resolve picks the concrete layout for auto and epoch from the first value.
+func (p *timeParser) resolve(s string, numeric bool) (string, error) {
+	layout := p.cfg.layout
+	if layout == layoutEpoch || (layout == layoutAuto && numeric) {
+		if !numeric {
+			return "", fmt.Errorf("%q is not a Unix time", s)
+		}
+		return "epoch_" + epochUnit(s), nil
+	}
+	if layout != layoutAuto {
+		return layout, nil
+	}
+	for _, l := range commonLayouts {
+		if _, err := time.ParseInLocation(l, s, p.cfg.loc); err == nil {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("%q matches none of the common time layouts; set one with --time-layout", s)
+}
+
+// epochUnit guesses the unit of a Unix time from its magnitude: seconds until
+// the year 5138, then milliseconds, microseconds and nanoseconds.
+func epochUnit(s string) string {
+	digits := strings.TrimPrefix(s, "-")
+	if i := strings.IndexAny(digits, ".eE"); i >= 0 {
+		digits = digits[:i]
+	}
+	switch n := len(strings.TrimLeft(digits, "0")); {
+	case n <= 11:
+		return "s"
+	case n <= 14:
+		return "ms"
+	case n <= 17:
+		return "us"
+	}
+	return "ns"
+}
+
+// fromUnits returns base plus s units, exactly: fractional seconds are
+// carried to the nanosecond rather than rounded through float64.
+func fromUnits(s string, unit time.Duration, base time.Time) (time.Time, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%q is not a number", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(int64(unit)))
+	ns := new(big.Int).Quo(r.Num(), r.Denom())
+	if !ns.IsInt64() {
+		return time.Time{}, fmt.Errorf("%q is out of range", s)
+	}
+	return base.Add(time.Duration(ns.Int64())).UTC(), nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Column type hints. Without one, coerce guesses from the text.
+const (
+	typeString    = "string"
+	typeInt       = "int"
+	typeDecimal   = "decimal"
+	typeBool      = "bool"
+	typeTimestamp = "timestamp"
+	typeJSON      = "json"
+)
+
+var typeHints = map[string]bool{
+	typeString: true, typeInt: true, typeDecimal: true,
+	typeBool: true, typeTimestamp: true, typeJSON: true,
+}
+
+// jsonNumber matches the JSON number grammar. Anything coerced to a number
+// must match it so the text can be emitted verbatim as a json.Number: no
+// leading zeros ("00123" stays a string), no NaN or Inf.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// parseTypes parses "col=type,col=type" as given to --types.
+func parseTypes(s string) (map[string]string, error) {
+	types := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		col, typ, ok := strings.Cut(part, "=")
+		if !ok || col == "" {
+			return nil, fmt.Errorf("--types: %q is not column=type", part)
+		}
+		types[col] = strings.TrimSpace(typ)
+	}
+	return types, nil
+}
+
+// checkTypes rejects unknown type names.
+func checkTypes(types map[string]string) error {
+	var bad []string
+	for col, t := range types {
+		if !typeHints[t] {
+			bad = append(bad, fmt.Sprintf("%s=%s", col, t))
+		}
+	}
+	if len(bad) > 0 {
+		sort.Strings(bad)
+		return fmt.Errorf("unknown column type(s) %s (expected string, int, decimal, bool, timestamp or json)", strings.Join(bad, ", "))
+	}
+	return nil
+}
+
+// convertValue converts v, either raw CSV text or a decoded JSON value, to the
+// hinted type. It fails rather than guess: a hinted column that does not parse
+// is a mapping error, not something to paper over in the output.
+func convertValue(v interface{}, hint string, tp *timeParser) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if hint == typeString {
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		case bool:
+			return fmt.Sprint(v), nil
+		default:
+			b, err := json.Marshal(v)
+			return string(b), err
+		}
+	}
+
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = strings.TrimSpace(v)
+	case json.Number:
+		s = v.String()
+	case bool:
+		if hint == typeBool {
+			return v, nil
+		}
+		s = fmt.Sprint(v)
+	default:
+		if hint == typeJSON {
+			return v, nil
+		}
+		return nil, fmt.Errorf("cannot convert %T to %s", v, hint)
+	}
+	if s == "" {
+		return nil, nil
+	}
+
+	switch hint {
+	case typeInt:
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("%q is not an integer", s)
+		}
+		return json.Number(n.String()), nil
+	case typeDecimal:
+		if !jsonNumber.MatchString(s) {
+			return nil, fmt.Errorf("%q is not a decimal number", s)
+		}
+		return json.Number(s), nil
+	case typeBool:
+		switch strings.ToLower(s) {
+		case "true", "t", "yes", "y", "1":
+			return true, nil
+		case "false", "f", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", s)
+	case typeTimestamp:
+		ts, err := tp.parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return ts.Format(time.RFC3339Nano), nil
+	case typeJSON:
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		var out interface{}
+		if err := dec.Decode(&out); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", hint)
+}
+
+// applyTypes converts the hinted columns of rec in place.
+func applyTypes(rec map[string]interface{}, opts csvOptions) error {
+	for col, hint := range opts.types {
+		v, ok := rec[col]
+		if !ok {
+			continue
+		}
+		out, err := convertValue(v, hint, opts.parser(col))
+		if err != nil {
+			return fmt.Errorf("column %q: %w", col, err)
+		}
+		rec[col] = out
+	}
+	return nil
+}